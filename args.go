@@ -1,6 +1,13 @@
 package main
 
-import "flag"
+import (
+	"encoding/json"
+	"flag"
+	"strings"
+	"time"
+
+	"github.com/unixpickle/optishop-server/serverapi"
+)
 
 type Args struct {
 	AssetDir   string
@@ -8,6 +15,16 @@ type Args struct {
 	Addr       string
 	NumProxies int
 	LocalMode  bool
+
+	RouteTimeout time.Duration
+
+	Admins string
+
+	Sources      string
+	LayoutDir    string
+	SourceConfig string
+
+	OverrideDir string
 }
 
 func (a *Args) Add() {
@@ -17,4 +34,57 @@ func (a *Args) Add() {
 	flag.IntVar(&a.NumProxies, "proxies", 0, "number of reverse proxies before this endpoint, "+
 		"for rate-limiting")
 	flag.BoolVar(&a.LocalMode, "local", false, "provide a user-free front-end")
+	flag.DurationVar(&a.RouteTimeout, "route-timeout", time.Second*5,
+		"maximum time to spend optimizing a route")
+	flag.StringVar(&a.Admins, "admins", "", "comma-separated usernames of administrators")
+	flag.StringVar(&a.Sources, "sources", "target",
+		"comma-separated store sources to enable (target, handmade)")
+	flag.StringVar(&a.LayoutDir, "layout-dir", "",
+		"directory of hand-authored layouts for the handmade source")
+	flag.StringVar(&a.SourceConfig, "source-config", "",
+		"JSON file configuring store sources (overrides -sources and -layout-dir)")
+	flag.StringVar(&a.OverrideDir, "override-dir", "",
+		"directory of corrections to store layouts (disabled if empty)")
+}
+
+// SourcesConfig reads the store source configuration file,
+// or creates a configuration from the other flags if no
+// file was specified.
+func (a *Args) SourcesConfig() (*serverapi.SourcesConfig, error) {
+	if a.SourceConfig != "" {
+		return serverapi.ReadSourcesConfig(a.SourceConfig)
+	}
+	config := &serverapi.SourcesConfig{}
+	for _, name := range a.SourceList() {
+		sourceConfig := &serverapi.SourceConfig{Name: name}
+		if name == "handmade" {
+			options, err := json.Marshal(map[string]string{"dir": a.LayoutDir})
+			if err != nil {
+				return nil, err
+			}
+			sourceConfig.Options = options
+		}
+		config.Sources = append(config.Sources, sourceConfig)
+	}
+	return config, nil
+}
+
+// AdminList gets the usernames of administrators.
+func (a *Args) AdminList() []string {
+	return splitList(a.Admins)
+}
+
+// SourceList gets the names of the enabled store sources.
+func (a *Args) SourceList() []string {
+	return splitList(a.Sources)
+}
+
+func splitList(list string) []string {
+	var res []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			res = append(res, name)
+		}
+	}
+	return res
 }
@@ -4,6 +4,7 @@
         constructor() {
             super();
             this.totalPrice = document.getElementById('total-price');
+            this.routeEstimate = document.getElementById('route-estimate');
             this.sortButton = document.getElementById('sort-button');
             this.sortButton.addEventListener('click', () => {
                 const hideLoader = showOverlayLoader();
@@ -13,6 +14,10 @@
             this.routeButton.addEventListener('click', () => {
                 window.open('/route?store=' + encodeURIComponent(currentStore()));
             });
+            this.printButton = document.getElementById('print-button');
+            this.printButton.addEventListener('click', () => {
+                window.open('/print?store=' + encodeURIComponent(currentStore()));
+            });
         }
 
         async sort() {
@@ -25,7 +30,15 @@
             if (data.error) {
                 throw data.error;
             }
-            this.updateData(data);
+            this.updateData(data.list);
+            this.showEstimate(data.total);
+        }
+
+        showEstimate(estimate) {
+            const minutes = Math.max(1, Math.round(estimate.time / 60));
+            const meters = Math.round(estimate.distance);
+            this.routeEstimate.textContent = 'About ' + minutes + ' min (' + meters + ' m)';
+            this.routeEstimate.style.display = 'block';
         }
 
         async fetchData() {
@@ -45,13 +58,16 @@
             this.totalPrice.style.display = 'block';
             this.sortButton.style.display = 'inline-block';
             this.routeButton.style.display = 'inline-block';
+            this.printButton.style.display = 'inline-block';
         }
 
         hideList() {
             super.hideList();
             this.totalPrice.style.display = 'none';
+            this.routeEstimate.style.display = 'none';
             this.sortButton.style.display = 'none';
             this.routeButton.style.display = 'none';
+            this.printButton.style.display = 'none';
         }
 
         dataChanged() {
@@ -135,6 +151,11 @@
                 throw data.error;
             }
             this.updateData(data);
+            if (this.routeEstimate.style.display === 'block') {
+                // The estimate is for the sorted list, so re-sort to
+                // get an estimate without the removed item.
+                await this.sort();
+            }
         }
     }
 
@@ -200,20 +221,16 @@
 
         findTextElements() {
             const svgElement = this.mapContainer.getElementsByTagName('svg')[0];
-            const texts = svgElement.getElementsByTagName('text');
+            const texts = svgElement.querySelectorAll('.zone-label[data-zone]');
             const counts = {};
-            for (let i = 0; i < texts.length; ++i) {
-                const text = texts[i].textContent;
-                counts[text] = (counts[text] || 0) + 1;
-            }
-            this.textLabels = [];
-            for (let i = 0; i < texts.length; ++i) {
-                const text = texts[i].textContent;
-                // Only find unique zone names.
-                if (counts[text] === 1) {
-                    this.textLabels.push(texts[i]);
-                }
-            }
+            texts.forEach((text) => {
+                const zone = text.getAttribute('data-zone');
+                counts[zone] = (counts[zone] || 0) + 1;
+            });
+            // Only find unique zone names.
+            this.textLabels = Array.prototype.filter.call(texts, (text) => {
+                return counts[text.getAttribute('data-zone')] === 1;
+            });
         }
 
         registerMouseEvents(onChosen) {
@@ -236,17 +253,51 @@
                 return closest;
             };
             let oldClosest = null;
+            let oldFill = null;
             svgElement.addEventListener('mousemove', (e) => {
                 if (oldClosest) {
-                    oldClosest.style.fill = 'black';
+                    oldClosest.style.fill = oldFill;
                 }
                 oldClosest = labelForEvent(e);
+                oldFill = oldClosest.style.fill;
                 oldClosest.style.fill = 'blue';
             });
-            svgElement.addEventListener('click', (e) => {
-                onChosen(labelForEvent(e).textContent);
+            svgElement.addEventListener('click', async (e) => {
+                const zone = await this.hitTest(svgElement, e);
+                onChosen(zone || labelForEvent(e).getAttribute('data-zone'));
             });
         }
+
+        async hitTest(svgElement, e) {
+            // Convert the click into the coordinates of the
+            // rendered map, which the server maps to a floor.
+            const point = svgElement.createSVGPoint();
+            point.x = e.clientX;
+            point.y = e.clientY;
+            const mapPoint = point.matrixTransform(svgElement.getScreenCTM().inverse());
+
+            const params = new URLSearchParams();
+            params.set('store', currentStore());
+            params.set('x', '' + mapPoint.x);
+            params.set('y', '' + mapPoint.y);
+            const floorGroup = e.target.closest('[data-floor]');
+            if (floorGroup) {
+                params.set('floor', floorGroup.getAttribute('data-floor'));
+            }
+            try {
+                const response = await fetch('/api/hittest?' + params.toString(), {
+                    credentials: 'same-origin',
+                    cache: 'no-store',
+                });
+                const data = await response.json();
+                if (data.error || data.inObstacle || !data.zones.length) {
+                    return null;
+                }
+                return data.zones[0].name;
+            } catch (e) {
+                return null;
+            }
+        }
     }
 
     function currentStore() {
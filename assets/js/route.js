@@ -3,10 +3,8 @@
     class RoutePage {
         constructor() {
             this.svgElement = document.getElementsByTagName('svg')[0];
-            this.labels = this.svgElement.getElementsByTagNameNS(
-                'http://www.w3.org/2000/svg',
-                'text',
-            );
+            this.labels = this.svgElement.querySelectorAll('.zone-label');
+            this.stopMarkers = this.svgElement.querySelectorAll('[data-stop]');
 
             this.listItems = LIST_DATA.map((x) => createListItem(x, 'div'));
             this.currentListItem = document.getElementById('current-list-item');
@@ -29,9 +27,56 @@
                 this.nextButton.blur();
             });
 
+            this.checkButton = document.getElementById('check-button');
+            this.checkButton.addEventListener('click', () => {
+                const hideLoader = showOverlayLoader();
+                this.checkOff().catch(handleError).finally(hideLoader);
+                this.checkButton.blur();
+            });
+
+            this.rerouteButton = document.getElementById('reroute-button');
+            this.rerouteButton.addEventListener('click', () => this.reroute());
+
+            this.showCurrentListItem();
+        }
+
+        async checkOff() {
+            // Checked off items are recorded in the list's
+            // history and removed from the list.
+            const current = LIST_DATA[this.currentIndex];
+            const params = new URLSearchParams(window.location.search);
+            const query = '?store=' + encodeURIComponent(params.get('store')) +
+                '&item=' + encodeURIComponent(current.id);
+            const response = await fetch('/api/checkitem' + query, {
+                credentials: 'same-origin',
+                cache: 'no-store',
+            });
+            const data = await response.json();
+            if (data.error) {
+                throw data.error;
+            }
+            this.listItems[this.currentIndex].classList.add('checked-off');
+            if (this.currentIndex + 1 < this.listItems.length) {
+                this.currentIndex++;
+            }
             this.showCurrentListItem();
         }
 
+        reroute() {
+            // Plan a new route from the current item through
+            // all of the items after it.
+            const current = LIST_DATA[this.currentIndex];
+            if (!current) {
+                return;
+            }
+            const remaining = LIST_DATA.slice(this.currentIndex + 1).map((x) => x.id);
+            const params = new URLSearchParams(window.location.search);
+            params.set('remaining', remaining.join(','));
+            params.set('startZone', current.zone);
+            params.delete('startFloor');
+            window.location.search = params.toString();
+        }
+
         showCurrentListItem() {
             const next = this.currentListItem.nextElementSibling;
             this.currentListItem.parentNode.removeChild(this.currentListItem);
@@ -39,6 +84,7 @@
             next.parentElement.insertBefore(this.currentListItem, next);
 
             this.emphasizeLabel(LIST_DATA[this.currentIndex].zone);
+            this.emphasizeStop(this.currentIndex + 1);
 
             if (this.currentIndex > 0) {
                 this.prevButton.classList.remove('page-button-disabled');
@@ -50,20 +96,33 @@
             } else {
                 this.nextButton.classList.add('page-button-disabled');
             }
+            if (this.currentListItem.classList.contains('checked-off')) {
+                this.checkButton.classList.add('page-button-disabled');
+            } else {
+                this.checkButton.classList.remove('page-button-disabled');
+            }
         }
 
         emphasizeLabel(text) {
             for (let i = 0; i < this.labels.length; ++i) {
                 const label = this.labels[i];
                 if (label.textContent.trim() === text) {
-                    label.style.fontWeight = 'bolder';
-                    label.style.fill = 'red';
+                    label.classList.add('current-zone');
                 } else {
-                    label.style.fontWeight = 'normal';
-                    label.style.fill = 'black';
+                    label.classList.remove('current-zone');
                 }
             }
         }
+
+        emphasizeStop(number) {
+            this.stopMarkers.forEach((marker) => {
+                if (marker.getAttribute('data-stop') === '' + number) {
+                    marker.classList.add('current-stop');
+                } else {
+                    marker.classList.remove('current-stop');
+                }
+            });
+        }
     }
 
     window.addEventListener('load', () => {
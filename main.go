@@ -23,7 +23,9 @@ func main() {
 	}
 	essentials.Must(err)
 
-	sources, err := serverapi.LoadStoreSources()
+	sourcesConfig, err := args.SourcesConfig()
+	essentials.Must(err)
+	sources, err := serverapi.LoadStoreSources(sourcesConfig)
 	essentials.Must(err)
 
 	server := &serverapi.Server{
@@ -33,6 +35,15 @@ func main() {
 		DB:         dbInstance,
 		Sources:    sources,
 		StoreCache: serverapi.NewStoreCache(sources),
+		Popularity: serverapi.NewPopularityCache(dbInstance),
+
+		RouteTimeout: args.RouteTimeout,
+		Admins:       args.AdminList(),
+	}
+	if args.OverrideDir != "" {
+		server.Overrides, err = serverapi.NewLayoutOverrides(args.OverrideDir)
+		essentials.Must(err)
+		server.StoreCache.Overrides = server.Overrides
 	}
 	server.AddRoutes()
 	mux := http.DefaultServeMux
@@ -2,6 +2,7 @@ package optishop
 
 import (
 	"math"
+	"sync"
 
 	"github.com/unixpickle/essentials"
 )
@@ -39,8 +40,13 @@ type BatchConnector interface {
 
 // A CacheConnector is a Connector that caches short paths
 // from another connector.
+//
+// A CacheConnector is safe to use from multiple Goroutines
+// so long as the underlying BatchConnector is.
 type CacheConnector struct {
-	bc    BatchConnector
+	bc BatchConnector
+
+	lock  sync.RWMutex
 	cache map[Point]map[Point]Path
 }
 
@@ -55,32 +61,61 @@ func (c *CacheConnector) Connect(a, b Point) Path {
 	if a == b {
 		return Path{a, b}
 	}
-	if _, ok := c.cache[a]; !ok {
-		c.addPoint(a)
-	}
-	if _, ok := c.cache[b]; !ok {
-		c.addPoint(b)
+	c.addPoint(a)
+	c.addPoint(b)
+	if path, ok := c.lookup(a, b); ok {
+		return path
 	}
+
+	// If a and b were added concurrently by different
+	// Goroutines, neither batch may include the other.
+	path := c.bc.ConnectBatch(a, []Point{b})[0]
+	c.lock.Lock()
+	c.cache[a][b] = path
+	c.lock.Unlock()
+	return path
+}
+
+func (c *CacheConnector) lookup(a, b Point) (Path, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
 	if path, ok := c.cache[a][b]; ok {
-		return path
+		return path, true
 	} else if path, ok = c.cache[b][a]; ok {
 		if path == nil {
-			return path
+			return path, true
 		}
 		rev := append(Path{}, path...)
 		essentials.Reverse(rev)
-		return rev
+		return rev, true
 	}
-	panic("no path in either direction")
+	return nil, false
 }
 
 func (c *CacheConnector) addPoint(p Point) {
-	other := c.points()
+	c.lock.RLock()
+	_, ok := c.cache[p]
+	var other []Point
+	if !ok {
+		other = c.points()
+	}
+	c.lock.RUnlock()
+	if ok {
+		return
+	}
+
+	// Run the (expensive) batch outside of the lock so that
+	// other Goroutines can keep using the cache.
 	paths := map[Point]Path{}
 	for i, p := range c.bc.ConnectBatch(p, other) {
 		paths[other[i]] = p
 	}
-	c.cache[p] = paths
+
+	c.lock.Lock()
+	if _, ok := c.cache[p]; !ok {
+		c.cache[p] = paths
+	}
+	c.lock.Unlock()
 }
 
 func (c *CacheConnector) points() []Point {
@@ -176,6 +211,58 @@ func (r *Raster) NonPreferred(p Point) bool {
 	return r.nonPreferred[r.pointToIndex(rp)]
 }
 
+// AddClearance expands the obstructed regions of the
+// raster (including the area outside the floor) so that
+// paths keep at least the given distance from them.
+func (r *Raster) AddClearance(clearance float64) {
+	radiusX := clearance * float64(r.width) / r.boundsWidth
+	radiusY := clearance * float64(r.height) / r.boundsHeight
+	maxX := int(math.Ceil(radiusX))
+	maxY := int(math.Ceil(radiusY))
+	if maxX == 0 && maxY == 0 {
+		return
+	}
+
+	newObstructed := append([]bool{}, r.obstructed...)
+	for y := 0; y < r.height; y++ {
+		for x := 0; x < r.width; x++ {
+			// Points past the edge of the raster are outside of
+			// the floor, so the edge itself is a boundary.
+			onEdge := x == 0 || y == 0 || x == r.width-1 || y == r.height-1
+			if !onEdge && !r.obstructionEdge(rasterPoint{X: x, Y: y}) {
+				continue
+			}
+			for dy := -maxY; dy <= maxY; dy++ {
+				for dx := -maxX; dx <= maxX; dx++ {
+					rp := rasterPoint{X: x + dx, Y: y + dy}
+					if rp.X < 0 || rp.Y < 0 || rp.X >= r.width || rp.Y >= r.height {
+						continue
+					}
+					nx, ny := float64(dx)/radiusX, float64(dy)/radiusY
+					if nx*nx+ny*ny <= 1 {
+						newObstructed[r.pointToIndex(rp)] = true
+					}
+				}
+			}
+		}
+	}
+	r.obstructed = newObstructed
+}
+
+// obstructionEdge checks if p is obstructed but borders
+// an unobstructed point.
+func (r *Raster) obstructionEdge(p rasterPoint) bool {
+	if !r.obstructed[r.pointToIndex(p)] {
+		return false
+	}
+	for _, n := range r.neighbors(p) {
+		if !r.obstructed[r.pointToIndex(n)] {
+			return true
+		}
+	}
+	return false
+}
+
 // Unobstruct finds a point close to p that is not
 // obstructed.
 func (r *Raster) Unobstruct(p Point) Point {
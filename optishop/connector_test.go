@@ -92,6 +92,35 @@ func TestRasterUnobstructEdgeCase(t *testing.T) {
 	}
 }
 
+func TestRasterAddClearance(t *testing.T) {
+	// A floor with a narrow gap between two shelves.
+	floor := &Floor{
+		Bounds: Polygon{{0, 0}, {10, 0}, {10, 10}, {0, 10}},
+		Obstacles: []Polygon{
+			{{0, 4}, {4.8, 4}, {4.8, 6}, {0, 6}},
+			{{5.2, 4}, {10, 4}, {10, 6}, {5.2, 6}},
+		},
+	}
+	raster := NewRasterSize(floor, 100, 100)
+	if raster.Obstructed(Point{X: 5, Y: 5}) {
+		t.Fatal("gap should not be obstructed")
+	}
+	if raster.Connect(Point{X: 5, Y: 1}, Point{X: 5, Y: 9}) == nil {
+		t.Fatal("expected path through gap")
+	}
+
+	raster.AddClearance(0.5)
+	if !raster.Obstructed(Point{X: 5, Y: 5}) {
+		t.Error("gap should be obstructed")
+	}
+	if raster.Obstructed(Point{X: 5, Y: 2}) {
+		t.Error("open area should not be obstructed")
+	}
+	if !raster.Obstructed(Point{X: 0.2, Y: 2}) {
+		t.Error("area near bounds should be obstructed")
+	}
+}
+
 func TestRasterConnectBatch(t *testing.T) {
 	var floor *Floor
 	essentials.Must(json.Unmarshal([]byte(connectorFloorData), &floor))
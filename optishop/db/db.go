@@ -2,7 +2,11 @@
 // the optishop server.
 package db
 
-import "github.com/unixpickle/optishop-server/optishop"
+import (
+	"time"
+
+	"github.com/unixpickle/optishop-server/optishop"
+)
 
 type UserID string
 
@@ -31,6 +35,18 @@ type ListEntryInfo struct {
 	InventoryProductData []byte
 	Zone                 *optishop.Zone
 	Floor                int
+
+	// Cold and Fragile mark items which should be picked
+	// up near the end of a trip.
+	Cold    bool
+	Fragile bool
+}
+
+// A HistoryEntry records a list entry which was checked
+// off during a trip.
+type HistoryEntry struct {
+	Info *ListEntryInfo
+	Time time.Time
 }
 
 type DB interface {
@@ -38,6 +54,7 @@ type DB interface {
 	Chpass(user UserID, old, new string) error
 	Login(username, password string) (UserID, error)
 	Username(user UserID) (string, error)
+	Users() ([]UserID, error)
 	UserMetadata(user UserID, field string) (string, error)
 	SetUserMetadata(user UserID, field, value string) error
 	Stores(user UserID) ([]*StoreRecord, error)
@@ -47,5 +64,8 @@ type DB interface {
 	ListEntries(user UserID, store StoreID) ([]*ListEntry, error)
 	AddListEntry(user UserID, store StoreID, info *ListEntryInfo) (ListEntryID, error)
 	RemoveListEntry(user UserID, store StoreID, entry ListEntryID) error
+	CheckOffListEntry(user UserID, store StoreID, entry ListEntryID) error
+	ListHistory(user UserID, store StoreID) ([]*HistoryEntry, error)
+	ModifyListEntry(user UserID, store StoreID, entry ListEntryID, f func(info *ListEntryInfo)) error
 	PermuteListEntries(user UserID, store StoreID, ids []ListEntryID) error
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/unixpickle/optishop-server/optishop"
@@ -68,6 +69,17 @@ func runGenericTests(t *testing.T, db DB) {
 		if uid, err := db.Login("joe", "ssap"); err != nil || uid != uid2 {
 			t.Error("login failed:", err)
 		}
+
+		users, err := db.Users()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Slice(users, func(i, j int) bool {
+			return users[i] < users[j]
+		})
+		if !reflect.DeepEqual(users, []UserID{uid1, uid2}) {
+			t.Errorf("unexpected users: %v", users)
+		}
 	})
 
 	t.Run("Metadata", func(t *testing.T) {
@@ -233,6 +245,54 @@ func runGenericTests(t *testing.T, db DB) {
 			t.Error("incorrect fields in second entry")
 		}
 
+		newInfo := &ListEntryInfo{
+			InventoryProductData: []byte("hello"),
+			Zone:                 &optishop.Zone{Name: "hi"},
+			Cold:                 true,
+		}
+		err = db.ModifyListEntry(user, store, newID1, func(info *ListEntryInfo) {
+			info.Cold = true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err = db.ListEntries(user, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != 2 {
+			t.Fatal("expected two entries but got:", len(list))
+		}
+		if list[0].ID != newID1 || !listEntriesEqual(list[0].Info, newInfo) {
+			t.Error("incorrect fields in modified entry")
+		}
+		if list[1].ID != newID2 || list[1].Info.Cold {
+			t.Error("incorrect fields in second entry after modification")
+		}
+
+		err = db.ModifyListEntry(user, store, newID2, func(info *ListEntryInfo) {
+			info.Fragile = true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = db.ModifyListEntry(user, store, "notarealid1231231", func(info *ListEntryInfo) {
+			t.Error("modified a missing entry")
+		})
+		if err == nil {
+			t.Error("modifying a missing entry should fail")
+		}
+		list, err = db.ListEntries(user, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if list[1].ID != newID2 || !list[1].Info.Fragile || list[1].Info.Zone.Name != "bye" {
+			t.Error("incorrect fields in second modified entry")
+		}
+		if list[0].ID != newID1 || !listEntriesEqual(list[0].Info, newInfo) {
+			t.Error("incorrect fields in first entry after second modification")
+		}
+
 		if err := db.RemoveListEntry(user, store, newID1); err != nil {
 			t.Fatal(err)
 		}
@@ -250,6 +310,79 @@ func runGenericTests(t *testing.T, db DB) {
 		}
 	})
 
+	t.Run("History", func(t *testing.T) {
+		user, err := db.CreateUser("historyTester", "pass", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		store, err := db.AddStore(user, &StoreInfo{
+			SourceName: "target",
+			StoreName:  "tribeca",
+			StoreData:  []byte("hello"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if history, err := db.ListHistory(user, store); err != nil {
+			t.Fatal(err)
+		} else if len(history) != 0 {
+			t.Fatal("expected empty history but got:", len(history))
+		}
+
+		info1 := &ListEntryInfo{
+			InventoryProductData: []byte("hello"),
+			Zone:                 &optishop.Zone{Name: "hi"},
+		}
+		info2 := &ListEntryInfo{
+			InventoryProductData: []byte("goodbye"),
+			Zone:                 &optishop.Zone{Name: "bye"},
+			Floor:                1,
+		}
+		newID1, err := db.AddListEntry(user, store, info1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		newID2, err := db.AddListEntry(user, store, info2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.CheckOffListEntry(user, store, newID2); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.CheckOffListEntry(user, store, newID2); err == nil {
+			t.Error("checking off a missing entry should fail")
+		}
+		if err := db.CheckOffListEntry(user, store, newID1); err != nil {
+			t.Fatal(err)
+		}
+
+		if list, err := db.ListEntries(user, store); err != nil {
+			t.Fatal(err)
+		} else if len(list) != 0 {
+			t.Error("expected empty list but got:", len(list))
+		}
+		history, err := db.ListHistory(user, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != 2 {
+			t.Fatal("expected two history entries but got:", len(history))
+		}
+		if !listEntriesEqual(history[0].Info, info2) || !listEntriesEqual(history[1].Info, info1) {
+			t.Error("incorrect history entries")
+		}
+		if history[0].Time.IsZero() || history[1].Time.Before(history[0].Time) {
+			t.Error("incorrect history times")
+		}
+
+		if err := db.RemoveStore(user, store); err != nil {
+			t.Fatal(err)
+		}
+	})
+
 	t.Run("Permute", func(t *testing.T) {
 		user, err := db.CreateUser("permuteTester", "pass", nil)
 		if err != nil {
@@ -329,5 +462,7 @@ func runGenericTests(t *testing.T, db DB) {
 func listEntriesEqual(l1, l2 *ListEntryInfo) bool {
 	return bytes.Equal(l1.InventoryProductData, l2.InventoryProductData) &&
 		reflect.DeepEqual(l1.Zone, l2.Zone) &&
-		l1.Floor == l2.Floor
+		l1.Floor == l2.Floor &&
+		l1.Cold == l2.Cold &&
+		l1.Fragile == l2.Fragile
 }
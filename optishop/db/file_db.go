@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 	"unicode"
 
 	"github.com/unixpickle/essentials"
@@ -23,6 +24,7 @@ const (
 	fileDBStores     = "stores"
 	fileDBUsername   = "username"
 	fileDBListPrefix = "store_"
+	fileDBHistory    = "history_"
 	fileDBMeta       = "meta_"
 )
 
@@ -155,6 +157,31 @@ func (f *FileDB) Username(user UserID) (string, error) {
 	return string(user), nil
 }
 
+func (f *FileDB) Users() ([]UserID, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	listing, err := ioutil.ReadDir(f.Dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "get users")
+	}
+	var users []UserID
+	for _, info := range listing {
+		if !info.IsDir() {
+			continue
+		}
+		path := filepath.Join(f.Dir, info.Name(), fileDBUsername)
+		username, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, errors.Wrap(err, "get users")
+		}
+		users = append(users, UserID(username))
+	}
+	return users, nil
+}
+
 func (f *FileDB) Stores(user UserID) ([]*StoreRecord, error) {
 	f.lock.RLock()
 	defer f.lock.RUnlock()
@@ -223,6 +250,10 @@ func (f *FileDB) RemoveStore(user UserID, store StoreID) error {
 			if err := f.deleteUserField(string(user), f.listField(store)); err != nil {
 				return errors.Wrap(err, "remove store")
 			}
+			err := f.deleteUserField(string(user), f.historyField(store))
+			if err != nil && !os.IsNotExist(err) {
+				return errors.Wrap(err, "remove store")
+			}
 			return nil
 		}
 	}
@@ -285,6 +316,85 @@ func (f *FileDB) RemoveListEntry(user UserID, store StoreID, entry ListEntryID)
 	return errors.New("remove list entry: entry not found")
 }
 
+// CheckOffListEntry removes an entry from a list and
+// records it in the list's history.
+func (f *FileDB) CheckOffListEntry(user UserID, store StoreID, entry ListEntryID) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var entries []*ListEntry
+	if err := f.decodeUserField(string(user), f.listField(store), &entries); err != nil {
+		return errors.Wrap(err, "check off list entry")
+	}
+	for i, e := range entries {
+		if e.ID != entry {
+			continue
+		}
+		history, err := f.readHistory(string(user), store)
+		if err != nil {
+			return errors.Wrap(err, "check off list entry")
+		}
+		history = append(history, &HistoryEntry{Info: e.Info, Time: time.Now()})
+		if err := f.encodeUserField(string(user), f.historyField(store), history); err != nil {
+			return errors.Wrap(err, "check off list entry")
+		}
+		essentials.OrderedDelete(&entries, i)
+		if err := f.encodeUserField(string(user), f.listField(store), &entries); err != nil {
+			return errors.Wrap(err, "check off list entry")
+		}
+		return nil
+	}
+	return errors.New("check off list entry: entry not found")
+}
+
+// ListHistory gets the entries which have been checked
+// off of a list, from oldest to newest.
+func (f *FileDB) ListHistory(user UserID, store StoreID) ([]*HistoryEntry, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	history, err := f.readHistory(string(user), store)
+	if err != nil {
+		return nil, errors.Wrap(err, "get list history")
+	}
+	return history, nil
+}
+
+func (f *FileDB) readHistory(username string, store StoreID) ([]*HistoryEntry, error) {
+	var history []*HistoryEntry
+	err := f.decodeUserField(username, f.historyField(store), &history)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return history, nil
+}
+
+// ModifyListEntry atomically updates the info of a list
+// entry by calling fn on it.
+//
+// The database is locked while fn runs, so fn should not
+// access the database.
+func (f *FileDB) ModifyListEntry(user UserID, store StoreID, entry ListEntryID,
+	fn func(info *ListEntryInfo)) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	var entries []*ListEntry
+	if err := f.decodeUserField(string(user), f.listField(store), &entries); err != nil {
+		return errors.Wrap(err, "modify list entry")
+	}
+	for _, e := range entries {
+		if e.ID == entry {
+			fn(e.Info)
+			if err := f.encodeUserField(string(user), f.listField(store), &entries); err != nil {
+				return errors.Wrap(err, "modify list entry")
+			}
+			return nil
+		}
+	}
+	return errors.New("modify list entry: entry not found")
+}
+
 func (f *FileDB) PermuteListEntries(user UserID, store StoreID, ids []ListEntryID) error {
 	f.lock.Lock()
 	defer f.lock.Unlock()
@@ -332,6 +442,12 @@ func (f *FileDB) listField(storeID StoreID) string {
 	return fileDBListPrefix + nameStr
 }
 
+func (f *FileDB) historyField(storeID StoreID) string {
+	nameHash := sha256.Sum256([]byte(storeID))
+	nameStr := base64.URLEncoding.EncodeToString(nameHash[:])
+	return fileDBHistory + nameStr
+}
+
 func (f *FileDB) readUserField(username, field string) ([]byte, error) {
 	path := filepath.Join(f.usernameDir(username), field)
 	return ioutil.ReadFile(path)
@@ -46,6 +46,10 @@ func (l *LocalDB) Username(user UserID) (string, error) {
 	return "", nil
 }
 
+func (l *LocalDB) Users() ([]UserID, error) {
+	return []UserID{""}, nil
+}
+
 func (l *LocalDB) UserMetadata(user UserID, field string) (string, error) {
 	return l.fileDB.UserMetadata(l.userID, field)
 }
@@ -82,6 +86,19 @@ func (l *LocalDB) RemoveListEntry(user UserID, store StoreID, entry ListEntryID)
 	return l.fileDB.RemoveListEntry(l.userID, store, entry)
 }
 
+func (l *LocalDB) CheckOffListEntry(user UserID, store StoreID, entry ListEntryID) error {
+	return l.fileDB.CheckOffListEntry(l.userID, store, entry)
+}
+
+func (l *LocalDB) ListHistory(user UserID, store StoreID) ([]*HistoryEntry, error) {
+	return l.fileDB.ListHistory(l.userID, store)
+}
+
+func (l *LocalDB) ModifyListEntry(user UserID, store StoreID, entry ListEntryID,
+	f func(info *ListEntryInfo)) error {
+	return l.fileDB.ModifyListEntry(l.userID, store, entry, f)
+}
+
 func (l *LocalDB) PermuteListEntries(user UserID, store StoreID, ids []ListEntryID) error {
 	return l.fileDB.PermuteListEntries(l.userID, store, ids)
 }
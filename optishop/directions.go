@@ -0,0 +1,140 @@
+package optishop
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// DirectionsSimplifyFrac is the fraction of the layout
+	// size below which wiggles in a path are ignored when
+	// generating directions.
+	DirectionsSimplifyFrac = 0.01
+
+	// LandmarkFrac is the fraction of the layout size
+	// within which a zone may be used as a landmark.
+	LandmarkFrac = 0.05
+)
+
+// A Direction is a single human-readable instruction for
+// following a FloorPath.
+type Direction struct {
+	// Floor is the index of the floor on which the
+	// instruction applies.
+	Floor int
+
+	// Location is the point at which the instruction
+	// applies, e.g. where to turn.
+	Location Point
+
+	Text string
+}
+
+// Directions generates turn-by-turn directions for a path
+// through a layout.
+//
+// Turns are described using nearby zones as landmarks,
+// floor changes are described using the portals in the
+// path, and the final direction names the zone at the end
+// of the path.
+func Directions(layout *Layout, path FloorPath) []*Direction {
+	size := LayoutSize(layout)
+	var res []*Direction
+	for i, step := range path {
+		if len(step.Path) == 0 {
+			continue
+		}
+		floor := layout.Floors[step.Floor]
+		points := step.Path.Simplify(size * DirectionsSimplifyFrac)
+
+		var lastLandmark *Zone
+		for j := 1; j+1 < len(points); j++ {
+			turn := turnDescription(points[j-1], points[j], points[j+1])
+			if turn == "" {
+				continue
+			}
+			text := capitalize(turn) + "."
+			landmark := nearestZone(floor, points[j], size*LandmarkFrac)
+			if landmark != nil && landmark != lastLandmark {
+				text = fmt.Sprintf("Walk past %s, then %s.", zoneDescription(landmark), turn)
+				lastLandmark = landmark
+			}
+			res = append(res, &Direction{Floor: step.Floor, Location: points[j], Text: text})
+		}
+
+		end := points[len(points)-1]
+		if i+1 < len(path) {
+			var text string
+			portal := layout.Portal(step.SourcePortal)
+			if portal == nil || portal.Type == "" {
+				text = fmt.Sprintf("Go to floor %d.", path[i+1].Floor+1)
+			} else {
+				text = fmt.Sprintf("Take the %s to floor %d.", portal.Type, path[i+1].Floor+1)
+			}
+			res = append(res, &Direction{Floor: step.Floor, Location: end, Text: text})
+		} else {
+			text := "Arrive at your destination."
+			if zone := nearestZone(floor, end, size*LandmarkFrac); zone != nil {
+				text = fmt.Sprintf("Arrive at %s.", zoneDescription(zone))
+			}
+			res = append(res, &Direction{Floor: step.Floor, Location: end, Text: text})
+		}
+	}
+	return res
+}
+
+// turnDescription describes the change in heading at
+// point b when walking from a to c.
+//
+// Returns "" if the heading does not change enough to be
+// considered a turn.
+func turnDescription(a, b, c Point) string {
+	d1 := b.Sub(a)
+	d2 := c.Sub(b)
+	cross := d1.X*d2.Y - d1.Y*d2.X
+	dot := d1.X*d2.X + d1.Y*d2.Y
+	angle := math.Atan2(cross, dot)
+
+	// Layout coordinates have the y-axis pointing down, so
+	// a positive angle is a clockwise (right) turn.
+	side := "right"
+	if angle < 0 {
+		side = "left"
+	}
+	switch abs := math.Abs(angle); {
+	case abs < math.Pi/6:
+		return ""
+	case abs < math.Pi/3:
+		return "bear " + side
+	case abs < math.Pi*5/6:
+		return "turn " + side
+	default:
+		return "turn around"
+	}
+}
+
+// nearestZone finds the named zone on the floor which is
+// closest to p, or returns nil if no named zone is within
+// maxDist of p.
+func nearestZone(floor *Floor, p Point, maxDist float64) *Zone {
+	z := floor.NearestZone(p)
+	if z == nil || z.Location.Distance(p) > maxDist {
+		return nil
+	}
+	return z
+}
+
+func zoneDescription(z *Zone) string {
+	if z.Specific {
+		return "aisle " + z.Name
+	}
+	return z.Name
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,40 @@
+package optishop
+
+import "testing"
+
+func TestDirections(t *testing.T) {
+	layout := portalChoiceLayout()
+	layout.Floors[0].Zones = []*Zone{
+		&Zone{Name: "G12", Location: Point{5, 0.3}, Specific: true},
+	}
+	layout.Floors[1].Zones = []*Zone{
+		&Zone{Name: "A4", Location: Point{2, 8}, Specific: true},
+	}
+	path := FloorPath{
+		&FloorPathStep{
+			Floor:        0,
+			Path:         Path{{0, 0}, {2, 0}, {5, 0}, {5, 4}, {5, 8}, {8, 8}},
+			SourcePortal: 2,
+			DestPortal:   3,
+		},
+		&FloorPathStep{
+			Floor: 1,
+			Path:  Path{{8, 8}, {5, 8.01}, {2, 8}},
+		},
+	}
+	expected := []*Direction{
+		{Floor: 0, Location: Point{5, 0}, Text: "Walk past aisle G12, then turn right."},
+		{Floor: 0, Location: Point{5, 8}, Text: "Turn left."},
+		{Floor: 0, Location: Point{8, 8}, Text: "Take the escalator to floor 2."},
+		{Floor: 1, Location: Point{2, 8}, Text: "Arrive at aisle A4."},
+	}
+	actual := Directions(layout, path)
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d directions but got %d", len(expected), len(actual))
+	}
+	for i, x := range expected {
+		if *actual[i] != *x {
+			t.Errorf("direction %d: expected %v but got %v", i, x, actual[i])
+		}
+	}
+}
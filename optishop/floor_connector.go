@@ -1,7 +1,11 @@
 package optishop
 
 import (
+	"context"
 	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
 
 	"github.com/unixpickle/essentials"
 )
@@ -18,9 +22,33 @@ type FloorPoint struct {
 // A FloorConnector combines Connectors from each floor
 // of a building to connect arbitrary locations in the
 // building.
+//
+// A FloorConnector is safe for concurrent use if all of
+// its Connectors are.
 type FloorConnector struct {
 	Connectors []Connector
 	Layout     *Layout
+
+	// PortalCosts specifies how expensive it is to use
+	// each type of portal.
+	//
+	// If nil, DefaultPortalCosts is used.
+	// Portals of a type missing from the map are given the
+	// largest cost of any type in the map.
+	PortalCosts map[PortalType]PortalCost
+
+	// MinimizeFloorChanges, if true, makes every portal so
+	// expensive that paths always go through as few
+	// portals as possible, ignoring PortalCosts.
+	MinimizeFloorChanges bool
+
+	// Profile, if non-nil, restricts the portals that
+	// paths may use.
+	//
+	// The clearance of the profile is not enforced here,
+	// since it is a property of the Connectors; see
+	// NewFloorConnectorProfile.
+	Profile *RoutingProfile
 }
 
 // NewFloorConnector creates a new FloorConnector using
@@ -51,11 +79,33 @@ func NewFloorConnectorCached(layout *Layout) *FloorConnector {
 	return conn
 }
 
+// NewFloorConnectorProfile is like
+// NewFloorConnectorCached, but the resulting connector
+// only finds paths allowed by the RoutingProfile.
+//
+// If profile is nil, this is equivalent to
+// NewFloorConnectorCached.
+func NewFloorConnectorProfile(layout *Layout, profile *RoutingProfile) *FloorConnector {
+	conn := &FloorConnector{
+		Layout:     layout,
+		Connectors: make([]Connector, len(layout.Floors)),
+		Profile:    profile,
+	}
+	for i, floor := range layout.Floors {
+		raster := NewRaster(floor)
+		if profile != nil && profile.Clearance > 0 {
+			raster.AddClearance(profile.Clearance)
+		}
+		conn.Connectors[i] = NewCacheConnector(raster)
+	}
+	return conn
+}
+
 // Connect finds a short FloorPath between points a and b.
 //
 // Returns nil if no path could be found.
 func (f *FloorConnector) Connect(a, b FloorPoint) FloorPath {
-	portalDistance := f.portalDistance()
+	costs := f.portalCosts()
 
 	queue := NewMinHeap()
 	visited := map[FloorPoint]*MinHeapNode{}
@@ -89,6 +139,9 @@ func (f *FloorConnector) Connect(a, b FloorPoint) FloorPath {
 		}
 
 		for _, portal := range f.Layout.Floors[fp.Floor].Portals {
+			if !f.Profile.AllowsPortal(portal.Type) {
+				continue
+			}
 			path := conn.Connect(fp.Point, portal.Location)
 			if path == nil {
 				continue
@@ -101,7 +154,7 @@ func (f *FloorConnector) Connect(a, b FloorPoint) FloorPath {
 					DestPortal:   dest,
 				}
 				node := &floorSearchNode{Step: step, Parent: prev}
-				addNode(node, dist+path.Length()+portalDistance)
+				addNode(node, dist+path.Length()+f.portalCost(costs, portal, dest))
 			}
 		}
 	}
@@ -130,32 +183,113 @@ func (f *FloorConnector) Connect(a, b FloorPoint) FloorPath {
 //
 // This distance function can be used with a TSP solver.
 //
+// Distances are computed in parallel across all CPUs, so
+// the Connectors must be safe for concurrent use.
+//
 // Returns nil if there are points that cannot reach each
 // other.
 func (f *FloorConnector) DistanceFunc(points []FloorPoint) func(idx1, idx2 int) float64 {
-	portalDist := f.portalDistance()
+	res, _ := f.DistanceFuncContext(context.Background(), points)
+	return res
+}
+
+// DistanceFuncContext is like DistanceFunc, but stops
+// early and returns ctx.Err() if ctx is done before all of
+// the distances are computed.
+//
+// The context is checked between rows of the distance
+// matrix, so a row which has been started is finished.
+func (f *FloorConnector) DistanceFuncContext(ctx context.Context,
+	points []FloorPoint) (func(idx1, idx2 int) float64, error) {
 	distances := make([][]float64, len(points))
-	for i, p := range points {
+	for i := range distances {
 		distances[i] = make([]float64, len(points))
-		for j, p1 := range points {
-			if p == p1 {
-				// Happens for items and themselves, and for items
-				// that are in the same aisle.
-				continue
-			}
-			path := f.Connect(p, p1)
-			if path == nil {
-				return nil
-			}
-			distances[i][j] = float64(len(path)) * portalDist
-			for _, part := range path {
-				distances[i][j] += part.Path.Length()
+	}
+
+	rows := make(chan int, len(points))
+	for i := range points {
+		rows <- i
+	}
+	close(rows)
+
+	var failed int32
+	var wg sync.WaitGroup
+	for worker := 0; worker < runtime.GOMAXPROCS(0); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rows {
+				if atomic.LoadInt32(&failed) != 0 || ctx.Err() != nil {
+					return
+				}
+				p := points[i]
+				for j, p1 := range points {
+					if p == p1 {
+						// Happens for items and themselves, and for items
+						// that are in the same aisle.
+						continue
+					}
+					path := f.Connect(p, p1)
+					if path == nil {
+						atomic.StoreInt32(&failed, 1)
+						return
+					}
+					distances[i][j] = f.PathCost(path)
+				}
 			}
-		}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if failed != 0 {
+		return nil, nil
 	}
 	return func(i, j int) float64 {
 		return distances[i][j]
+	}, nil
+}
+
+// PathCost computes the total cost of a path, including
+// the distance walked on each floor and the cost of every
+// portal used along the way.
+func (f *FloorConnector) PathCost(path FloorPath) float64 {
+	costs := f.portalCosts()
+	var res float64
+	for i, step := range path {
+		res += step.Path.Length()
+		if i+1 < len(path) {
+			res += f.portalCost(costs, f.Layout.Portal(step.SourcePortal), step.DestPortal)
+		}
+	}
+	return res
+}
+
+func (f *FloorConnector) portalCosts() map[PortalType]PortalCost {
+	if f.PortalCosts == nil {
+		return DefaultPortalCosts(f.Layout)
 	}
+	return f.PortalCosts
+}
+
+// portalCost computes the cost of going through a portal
+// to one of its destinations.
+func (f *FloorConnector) portalCost(costs map[PortalType]PortalCost, source *Portal,
+	destID int) float64 {
+	if f.MinimizeFloorChanges {
+		return f.portalDistance()
+	}
+	numFloors := f.Layout.PortalFloor(f.Layout.Portal(destID)) - f.Layout.PortalFloor(source)
+	if cost, ok := costs[source.Type]; ok {
+		return cost.Cost(numFloors)
+	}
+	var maxCost float64
+	for _, cost := range costs {
+		maxCost = math.Max(maxCost, cost.Cost(numFloors))
+	}
+	return maxCost
 }
 
 // portalDistance gets a relatively long distance that can
@@ -164,12 +298,7 @@ func (f *FloorConnector) DistanceFunc(points []FloorPoint) func(idx1, idx2 int)
 // portals will always be considered more expensive than
 // walking within a given floor.
 func (f *FloorConnector) portalDistance() float64 {
-	largeDistance := 0.0
-	for _, floor := range f.Layout.Floors {
-		_, _, w, h := floor.Bounds.Bounds()
-		largeDistance = math.Max(largeDistance, math.Max(w, h))
-	}
-	return largeDistance * 100
+	return LayoutSize(f.Layout) * 100
 }
 
 // A FloorPathStep is a single step in a path between two
@@ -1,6 +1,11 @@
 package optishop
 
-import "testing"
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+)
 
 func TestFloorConnector(t *testing.T) {
 	layout := complexMultiFloorLayout()
@@ -57,6 +62,200 @@ func TestFloorConnector(t *testing.T) {
 	})
 }
 
+func TestFloorConnectorPortalCosts(t *testing.T) {
+	layout := portalChoiceLayout()
+	start := FloorPoint{Point: Point{1, 1}, Floor: 0}
+	end := FloorPoint{Point: Point{7, 6}, Floor: 1}
+
+	t.Run("MinimizeFloorChanges", func(t *testing.T) {
+		conn := testingSmallFloorConnector(layout, false)
+		conn.MinimizeFloorChanges = true
+		route := conn.Connect(start, end)
+		if len(route) != 2 {
+			t.Fatal("expected two steps, but got", len(route))
+		}
+		if route[0].SourcePortal != 0 {
+			t.Error("expected closest portal to be used")
+		}
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		conn := testingSmallFloorConnector(layout, false)
+		conn.PortalCosts = map[PortalType]PortalCost{
+			Elevator:  PortalCost{Fixed: 10},
+			Escalator: PortalCost{PerFloor: 1},
+		}
+		route := conn.Connect(start, end)
+		if len(route) != 2 {
+			t.Fatal("expected two steps, but got", len(route))
+		}
+		if route[0].SourcePortal != 2 {
+			t.Error("expected escalator to be used")
+		}
+		walking := route[0].Path.Length() + route[1].Path.Length()
+		if cost := conn.PathCost(route); math.Abs(cost-(walking+1)) > 1e-8 {
+			t.Errorf("expected cost %f but got %f", walking+1, cost)
+		}
+	})
+}
+
+func TestFloorConnectorProfile(t *testing.T) {
+	layout := portalChoiceLayout()
+	conn := testingSmallFloorConnector(layout, false)
+	conn.PortalCosts = map[PortalType]PortalCost{
+		Elevator:  PortalCost{Fixed: 10},
+		Escalator: PortalCost{PerFloor: 1},
+	}
+	conn.Profile = AccessibleProfile(0)
+	route := conn.Connect(FloorPoint{Point: Point{1, 1}, Floor: 0},
+		FloorPoint{Point: Point{7, 6}, Floor: 1})
+	if len(route) != 2 {
+		t.Fatal("expected two steps, but got", len(route))
+	}
+	if route[0].SourcePortal != 0 {
+		t.Error("expected elevator to be used")
+	}
+
+	conn.Profile = &RoutingProfile{PortalTypes: []PortalType{Stairs}}
+	route = conn.Connect(FloorPoint{Point: Point{1, 1}, Floor: 0},
+		FloorPoint{Point: Point{7, 6}, Floor: 1})
+	if route != nil {
+		t.Error("expected no route without allowed portals")
+	}
+}
+
+func TestFloorConnectorDistanceFunc(t *testing.T) {
+	layout := complexMultiFloorLayout()
+	points := testingFloorPoints(layout)
+
+	conn := testingSmallFloorConnector(layout, false)
+	actual := conn.DistanceFunc(points)
+	if actual == nil {
+		t.Fatal("unexpected nil distance function")
+	}
+
+	// Compute the distances serially to make sure that
+	// the parallel implementation matches.
+	for i, p := range points {
+		for j, p1 := range points {
+			var expected float64
+			if p != p1 {
+				expected = conn.PathCost(conn.Connect(p, p1))
+			}
+			if a := actual(i, j); a != expected {
+				t.Errorf("distance %d->%d: expected %f but got %f", i, j, expected, a)
+			}
+		}
+	}
+}
+
+func TestFloorConnectorDistanceFuncCancel(t *testing.T) {
+	layout := complexMultiFloorLayout()
+	points := testingFloorPoints(layout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	conn := testingSmallFloorConnector(layout, false)
+	actual, err := conn.DistanceFuncContext(ctx, points)
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled but got %v", err)
+	}
+	if actual != nil {
+		t.Error("expected nil distance function")
+	}
+}
+
+func TestFloorConnectorConcurrent(t *testing.T) {
+	layout := complexMultiFloorLayout()
+	points := testingFloorPoints(layout)
+	expected := testingSmallFloorConnector(layout, false).DistanceFunc(points)
+
+	t.Run("Connect", func(t *testing.T) {
+		conn := testingSmallFloorConnector(layout, true)
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := range points {
+					a := points[(i+g)%len(points)]
+					b := points[(i*3+g)%len(points)]
+					path := conn.Connect(a, b)
+					if path == nil {
+						t.Errorf("no path from %v to %v", a, b)
+						continue
+					}
+					first := path[0].Path[0]
+					last := path[len(path)-1].Path[len(path[len(path)-1].Path)-1]
+					if first != a.Point || last != b.Point {
+						t.Errorf("unexpected endpoints for path from %v to %v", a, b)
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+	})
+
+	t.Run("DistanceFunc", func(t *testing.T) {
+		conn := testingSmallFloorConnector(layout, true)
+		results := make([]func(i, j int) float64, 4)
+		var wg sync.WaitGroup
+		for g := range results {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				results[g] = conn.DistanceFunc(points)
+			}(g)
+		}
+		wg.Wait()
+		for _, actual := range results {
+			if actual == nil {
+				t.Fatal("unexpected nil distance function")
+			}
+			for i := range points {
+				for j := range points {
+					// Cached paths may be reversed versions of
+					// paths found in the other direction.
+					e, a := expected(i, j), actual(i, j)
+					if math.Abs(e-a) > 0.1*math.Max(e, 1) {
+						t.Errorf("distance %d->%d: expected about %f but got %f", i, j, e, a)
+					}
+				}
+			}
+		}
+	})
+}
+
+// testingSmallFloorConnector creates a FloorConnector
+// with low-resolution rasters to make tests fast.
+func testingSmallFloorConnector(layout *Layout, cached bool) *FloorConnector {
+	conn := &FloorConnector{Layout: layout}
+	for _, floor := range layout.Floors {
+		_, _, w, h := floor.Bounds.Bounds()
+		raster := NewRasterSize(floor, int(w*10), int(h*10))
+		if cached {
+			conn.Connectors = append(conn.Connectors, NewCacheConnector(raster))
+		} else {
+			conn.Connectors = append(conn.Connectors, raster)
+		}
+	}
+	return conn
+}
+
+func testingFloorPoints(layout *Layout) []FloorPoint {
+	var points []FloorPoint
+	for i, floor := range layout.Floors {
+		for _, zone := range floor.Zones {
+			points = append(points, FloorPoint{Point: zone.Location, Floor: i})
+		}
+	}
+	return append(points,
+		FloorPoint{Point: Point{2, 1}, Floor: 0},
+		FloorPoint{Point: Point{4, 1}, Floor: 1},
+		FloorPoint{Point: Point{5, 2}, Floor: 2},
+	)
+}
+
 func complexMultiFloorLayout() *Layout {
 	return &Layout{
 		Floors: []*Floor{
@@ -125,3 +324,45 @@ func complexMultiFloorLayout() *Layout {
 		},
 	}
 }
+
+func portalChoiceLayout() *Layout {
+	bounds := Polygon{{0, 0}, {10, 0}, {10, 10}, {0, 10}}
+	return &Layout{
+		Floors: []*Floor{
+			&Floor{
+				Bounds: bounds,
+				Portals: []*Portal{
+					&Portal{
+						Location:     Point{2, 1},
+						Type:         Elevator,
+						ID:           0,
+						Destinations: []int{1},
+					},
+					&Portal{
+						Location:     Point{8, 8},
+						Type:         Escalator,
+						ID:           2,
+						Destinations: []int{3},
+					},
+				},
+			},
+			&Floor{
+				Bounds: bounds,
+				Portals: []*Portal{
+					&Portal{
+						Location:     Point{2, 1},
+						Type:         Elevator,
+						ID:           1,
+						Destinations: []int{0},
+					},
+					&Portal{
+						Location:     Point{8, 8},
+						Type:         Escalator,
+						ID:           3,
+						Destinations: []int{2},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,101 @@
+// Package floorplan converts floor plans in standard
+// formats, such as SVG and GeoJSON, into store layouts.
+//
+// A floor plan is divided into layers: walls, which bound
+// the floor; shelves, which are obstacles; pads, which are
+// non-preferred areas; and labels, which name zones.
+package floorplan
+
+import (
+	"math"
+	"strings"
+
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+// Layers names the layers of a floor plan which contain
+// each kind of feature.
+//
+// For SVG, the names are element IDs. For GeoJSON, they
+// are values of a feature property.
+type Layers struct {
+	Walls   string
+	Shelves string
+	Pads    string
+	Labels  string
+}
+
+// DefaultLayers is the default naming for layers.
+var DefaultLayers = Layers{
+	Walls:   "walls",
+	Shelves: "shelves",
+	Pads:    "pads",
+	Labels:  "labels",
+}
+
+// A Label is a piece of text in a floor plan.
+type Label struct {
+	Text     string
+	Location optishop.Point
+}
+
+// NewFloor creates a floor from the features of a floor
+// plan.
+//
+// The largest wall polygon becomes the bounds of the
+// floor. Labels named "entrance" or "checkout" become
+// entrance and checkout zones, and all other labels become
+// specific zones.
+func NewFloor(walls, shelves, pads []optishop.Polygon, labels []*Label) *optishop.Floor {
+	floor := &optishop.Floor{
+		Bounds:    LargestPolygon(walls),
+		Obstacles: shelves,
+	}
+	for _, pad := range pads {
+		floor.NonPreferred = append(floor.NonPreferred, &optishop.NonPreferred{
+			Bounds:  pad,
+			Visible: true,
+		})
+	}
+	for _, label := range labels {
+		name := strings.TrimSpace(label.Text)
+		if name == "" {
+			continue
+		}
+		zone := &optishop.Zone{Name: name, Location: label.Location}
+		switch strings.ToLower(name) {
+		case "entrance":
+			zone.Entrance = true
+		case "checkout":
+			zone.Checkout = true
+		default:
+			zone.Specific = true
+		}
+		floor.Zones = append(floor.Zones, zone)
+	}
+	return floor
+}
+
+// LargestPolygon finds the polygon with the largest area.
+//
+// Returns nil if there are no polygons.
+func LargestPolygon(polys []optishop.Polygon) optishop.Polygon {
+	var res optishop.Polygon
+	var resArea float64
+	for _, p := range polys {
+		if area := polygonArea(p); res == nil || area > resArea {
+			res = p
+			resArea = area
+		}
+	}
+	return res
+}
+
+func polygonArea(p optishop.Polygon) float64 {
+	var sum float64
+	for i, p1 := range p {
+		p2 := p[(i+1)%len(p)]
+		sum += p1.X*p2.Y - p2.X*p1.Y
+	}
+	return math.Abs(sum / 2)
+}
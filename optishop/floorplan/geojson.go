@@ -0,0 +1,261 @@
+package floorplan
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+const metersPerDegree = 2 * math.Pi * 6371000 / 360
+
+// GeoJSONOptions specifies how to interpret the features
+// of a GeoJSON floor plan.
+type GeoJSONOptions struct {
+	Layers Layers
+
+	// LayerProperty is the feature property which names the
+	// layer of a feature.
+	LayerProperty string
+
+	// NameProperty is the feature property containing the
+	// text of labels.
+	NameProperty string
+
+	// FloorProperty is the feature property which names the
+	// floor of a feature. Features without it are grouped
+	// into a floor with an empty name.
+	FloorProperty string
+
+	// Planar, if true, indicates that coordinates are
+	// already in a flat coordinate system rather than being
+	// longitudes and latitudes.
+	//
+	// Otherwise, coordinates are projected to meters.
+	Planar bool
+}
+
+// DefaultGeoJSONOptions are the default options for
+// ParseGeoJSON.
+var DefaultGeoJSONOptions = GeoJSONOptions{
+	Layers:        DefaultLayers,
+	LayerProperty: "layer",
+	NameProperty:  "name",
+	FloorProperty: "floor",
+}
+
+type geoJSONFeature struct {
+	Properties map[string]interface{} `json:"properties"`
+	Geometry   *struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+}
+
+// ParseGeoJSON creates a layout from a GeoJSON feature
+// collection.
+//
+// Floors are ordered numerically if every floor name is a
+// number, and otherwise by their first appearance.
+//
+// Since north is up in a map but y points down in a
+// layout, the y axis is flipped.
+func ParseGeoJSON(data []byte, opts *GeoJSONOptions) (*optishop.Layout, error) {
+	var collection struct {
+		Type     string            `json:"type"`
+		Features []*geoJSONFeature `json:"features"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, errors.Wrap(err, "parse GeoJSON")
+	}
+	if collection.Type != "FeatureCollection" {
+		return nil, errors.New("parse GeoJSON: expected FeatureCollection but got " +
+			collection.Type)
+	}
+
+	type floorFeatures struct {
+		Walls   []optishop.Polygon
+		Shelves []optishop.Polygon
+		Pads    []optishop.Polygon
+		Labels  []*Label
+	}
+	floors := map[string]*floorFeatures{}
+	var floorNames []string
+
+	var allPoints []*optishop.Point
+	for i, feature := range collection.Features {
+		if feature.Geometry == nil {
+			continue
+		}
+		layer := stringProperty(feature.Properties, opts.LayerProperty)
+		if layer == "" || (layer != opts.Layers.Walls && layer != opts.Layers.Shelves &&
+			layer != opts.Layers.Pads && layer != opts.Layers.Labels) {
+			continue
+		}
+		floorName := stringProperty(feature.Properties, opts.FloorProperty)
+		floor, ok := floors[floorName]
+		if !ok {
+			floor = &floorFeatures{}
+			floors[floorName] = floor
+			floorNames = append(floorNames, floorName)
+		}
+
+		polys, err := geometryPolygons(feature.Geometry.Type, feature.Geometry.Coordinates)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse GeoJSON: feature "+strconv.Itoa(i))
+		}
+		for _, poly := range polys {
+			for j := range poly {
+				allPoints = append(allPoints, &poly[j])
+			}
+		}
+
+		switch layer {
+		case opts.Layers.Walls:
+			floor.Walls = append(floor.Walls, polys...)
+		case opts.Layers.Shelves:
+			floor.Shelves = append(floor.Shelves, polys...)
+		case opts.Layers.Pads:
+			floor.Pads = append(floor.Pads, polys...)
+		case opts.Layers.Labels:
+			for _, poly := range polys {
+				label := &Label{
+					Text:     stringProperty(feature.Properties, opts.NameProperty),
+					Location: polygonCenter(poly),
+				}
+				floor.Labels = append(floor.Labels, label)
+				// The centers are computed before projection, so
+				// they must be projected as well.
+				allPoints = append(allPoints, &label.Location)
+			}
+		}
+	}
+	if len(floorNames) == 0 {
+		return nil, errors.New("parse GeoJSON: no features in any layer")
+	}
+	projectPoints(allPoints, opts.Planar)
+	sortFloorNames(floorNames)
+
+	layout := &optishop.Layout{}
+	for _, name := range floorNames {
+		features := floors[name]
+		if len(features.Walls) == 0 {
+			return nil, errors.New("parse GeoJSON: no walls for floor " + strconv.Quote(name))
+		}
+		floor := NewFloor(features.Walls, features.Shelves, features.Pads, features.Labels)
+		floor.Name = name
+		layout.Floors = append(layout.Floors, floor)
+	}
+	return layout, nil
+}
+
+// geometryPolygons converts a geometry into polygons.
+//
+// Points become single-point polygons, and only the outer
+// ring of a polygon is used.
+func geometryPolygons(geomType string, coords json.RawMessage) ([]optishop.Polygon, error) {
+	switch geomType {
+	case "Point":
+		var point [2]float64
+		if err := json.Unmarshal(coords, &point); err != nil {
+			return nil, err
+		}
+		return []optishop.Polygon{{{X: point[0], Y: point[1]}}}, nil
+	case "Polygon":
+		var rings [][][2]float64
+		if err := json.Unmarshal(coords, &rings); err != nil {
+			return nil, err
+		}
+		if len(rings) == 0 {
+			return nil, nil
+		}
+		return []optishop.Polygon{ringPolygon(rings[0])}, nil
+	case "MultiPolygon":
+		var polys [][][][2]float64
+		if err := json.Unmarshal(coords, &polys); err != nil {
+			return nil, err
+		}
+		var res []optishop.Polygon
+		for _, rings := range polys {
+			if len(rings) > 0 {
+				res = append(res, ringPolygon(rings[0]))
+			}
+		}
+		return res, nil
+	}
+	return nil, errors.New("unsupported geometry type: " + geomType)
+}
+
+func ringPolygon(ring [][2]float64) optishop.Polygon {
+	// GeoJSON rings repeat the first point at the end.
+	if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+		ring = ring[:len(ring)-1]
+	}
+	res := make(optishop.Polygon, len(ring))
+	for i, p := range ring {
+		res[i] = optishop.Point{X: p[0], Y: p[1]}
+	}
+	return res
+}
+
+func polygonCenter(poly optishop.Polygon) optishop.Point {
+	var res optishop.Point
+	for _, p := range poly {
+		res.X += p.X / float64(len(poly))
+		res.Y += p.Y / float64(len(poly))
+	}
+	return res
+}
+
+// projectPoints converts points in place from GeoJSON
+// coordinates to layout coordinates, with the top-left
+// corner of the bounding box at the origin.
+func projectPoints(points []*optishop.Point, planar bool) {
+	if len(points) == 0 {
+		return
+	}
+	minX, maxY := math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		minX = math.Min(minX, p.X)
+		maxY = math.Max(maxY, p.Y)
+	}
+	scaleX, scaleY := 1.0, 1.0
+	if !planar {
+		scaleX = metersPerDegree * math.Cos(maxY*math.Pi/180)
+		scaleY = metersPerDegree
+	}
+	for _, p := range points {
+		p.X = (p.X - minX) * scaleX
+		p.Y = (maxY - p.Y) * scaleY
+	}
+}
+
+func stringProperty(props map[string]interface{}, name string) string {
+	switch x := props[name].(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+func sortFloorNames(names []string) {
+	for _, name := range names {
+		if _, err := strconv.ParseFloat(name, 64); err != nil {
+			return
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		x, _ := strconv.ParseFloat(names[i], 64)
+		y, _ := strconv.ParseFloat(names[j], 64)
+		return x < y
+	})
+}
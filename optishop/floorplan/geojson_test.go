@@ -0,0 +1,60 @@
+package floorplan
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+func TestParseGeoJSON(t *testing.T) {
+	data := []byte(`{
+  "type": "FeatureCollection",
+  "features": [
+    {"type": "Feature", "properties": {"layer": "walls", "floor": 2},
+     "geometry": {"type": "Polygon", "coordinates": [[[0,0],[20,0],[20,10],[0,10],[0,0]]]}},
+    {"type": "Feature", "properties": {"layer": "walls", "floor": 1},
+     "geometry": {"type": "Polygon", "coordinates": [[[0,0],[20,0],[20,10],[0,10],[0,0]]]}},
+    {"type": "Feature", "properties": {"layer": "shelves", "floor": 1},
+     "geometry": {"type": "MultiPolygon", "coordinates": [
+       [[[2,2],[4,2],[4,4],[2,4],[2,2]]], [[[6,2],[8,2],[8,4],[6,4],[6,2]]]
+     ]}},
+    {"type": "Feature", "properties": {"layer": "labels", "floor": 1, "name": "Checkout"},
+     "geometry": {"type": "Point", "coordinates": [15, 8]}},
+    {"type": "Feature", "properties": {"layer": "doors", "floor": 3},
+     "geometry": {"type": "LineString", "coordinates": [[0,0],[1,1]]}}
+  ]
+}`)
+	opts := DefaultGeoJSONOptions
+	opts.Planar = true
+	layout, err := ParseGeoJSON(data, &opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layout.Floors) != 2 || layout.Floors[0].Name != "1" || layout.Floors[1].Name != "2" {
+		t.Fatalf("unexpected floors: %v", layout.Floors)
+	}
+	floor := layout.Floors[0]
+	expectedBounds := optishop.Polygon{{X: 0, Y: 10}, {X: 20, Y: 10}, {X: 20, Y: 0}, {X: 0, Y: 0}}
+	if !polygonsClose(floor.Bounds, expectedBounds) {
+		t.Errorf("unexpected bounds: %v", floor.Bounds)
+	}
+	if len(floor.Obstacles) != 2 {
+		t.Errorf("unexpected obstacles: %v", floor.Obstacles)
+	}
+	if len(floor.Zones) != 1 || !floor.Zones[0].Checkout ||
+		floor.Zones[0].Location != (optishop.Point{X: 15, Y: 2}) {
+		t.Errorf("unexpected zones: %v", floor.Zones)
+	}
+
+	// Without the planar option, coordinates are treated as
+	// degrees and converted to meters.
+	layout, err = ParseGeoJSON(data, &DefaultGeoJSONOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, width, _ := layout.Floors[0].Bounds.Bounds()
+	if expected := 20 * metersPerDegree * math.Cos(10*math.Pi/180); math.Abs(width-expected) > 1e-3 {
+		t.Errorf("expected width %f but got %f", expected, width)
+	}
+}
@@ -0,0 +1,536 @@
+package floorplan
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/unixpickle/optishop-server/optishop"
+	"github.com/yhat/scrape"
+	"golang.org/x/net/html"
+)
+
+// ParseSVG creates a floor from an SVG floor plan.
+//
+// Each layer is the element with the layer's name as its
+// ID. Shapes in the walls, shelves, and pads layers may be
+// paths, polygons, polylines, or rects, and labels are the
+// text elements in the labels layer.
+//
+// The walls layer is required, but the other layers are
+// skipped if they are missing.
+func ParseSVG(data []byte, layers *Layers) (*optishop.Floor, error) {
+	parsed, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrap(err, "parse SVG")
+	}
+
+	layerPolygons := func(id string) ([]optishop.Polygon, error) {
+		elem, ok := findLayer(parsed, id)
+		if !ok {
+			return nil, nil
+		}
+		t, err := AncestorTransform(elem)
+		if err != nil {
+			return nil, err
+		}
+		return PathPolygons(parsed, id, t)
+	}
+
+	if _, ok := findLayer(parsed, layers.Walls); !ok {
+		return nil, errors.New("parse SVG: missing walls layer: " + layers.Walls)
+	}
+	walls, err := layerPolygons(layers.Walls)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse SVG")
+	} else if len(walls) == 0 {
+		return nil, errors.New("parse SVG: no shapes in walls layer")
+	}
+	shelves, err := layerPolygons(layers.Shelves)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse SVG")
+	}
+	pads, err := layerPolygons(layers.Pads)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse SVG")
+	}
+
+	var labels []*Label
+	if elem, ok := findLayer(parsed, layers.Labels); ok {
+		t, err := AncestorTransform(elem)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse SVG")
+		}
+		labels, err = TextLabels(parsed, layers.Labels, t)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse SVG")
+		}
+	}
+
+	return NewFloor(walls, shelves, pads, labels), nil
+}
+
+func findLayer(doc *html.Node, id string) (*html.Node, bool) {
+	if id == "" {
+		return nil, false
+	}
+	return scrape.Find(doc, scrape.ById(id))
+}
+
+// FindTag finds all of the descendants of an element with
+// a given tag name.
+func FindTag(elem *html.Node, tag string) []*html.Node {
+	return scrape.FindAll(elem, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == tag
+	})
+}
+
+// PathPolygons finds the polygons of all of the shapes
+// inside the element with the given ID.
+//
+// The transform t is applied to every shape, in addition
+// to the transforms of the element and its descendants.
+func PathPolygons(container *html.Node, id string, t *Transform) ([]optishop.Polygon, error) {
+	elem, ok := scrape.Find(container, scrape.ById(id))
+	if !ok {
+		return nil, errors.New("missing '" + id + "' group")
+	}
+	var res []optishop.Polygon
+	err := walkTransformed(elem, t, func(n *html.Node, t *Transform) error {
+		polys, err := shapePolygons(n, t)
+		if err != nil {
+			return err
+		}
+		res = append(res, polys...)
+		return nil
+	})
+	return res, err
+}
+
+// TextLabels finds all of the text elements inside the
+// element with the given ID.
+//
+// The transform t is applied like it is for PathPolygons.
+func TextLabels(container *html.Node, id string, t *Transform) ([]*Label, error) {
+	elem, ok := scrape.Find(container, scrape.ById(id))
+	if !ok {
+		return nil, errors.New("missing '" + id + "' group")
+	}
+	var res []*Label
+	err := walkTransformed(elem, t, func(n *html.Node, t *Transform) error {
+		if n.Data != "text" {
+			return nil
+		}
+		x, err := coordAttr(n, "x")
+		if err != nil {
+			return err
+		}
+		y, err := coordAttr(n, "y")
+		if err != nil {
+			return err
+		}
+		res = append(res, &Label{
+			Text:     strings.TrimSpace(scrape.Text(n)),
+			Location: t.Apply(optishop.Point{X: x, Y: y}),
+		})
+		return nil
+	})
+	return res, err
+}
+
+// walkTransformed calls f for n and every element inside
+// of it, along with the transform for each element.
+//
+// The children of text elements are not visited.
+func walkTransformed(n *html.Node, t *Transform, f func(n *html.Node, t *Transform) error) error {
+	if n.Type != html.ElementNode {
+		return nil
+	}
+	local, err := ParseTransform(scrape.Attr(n, "transform"))
+	if err != nil {
+		return err
+	}
+	t = t.Mul(local)
+	if err := f(n, t); err != nil {
+		return err
+	}
+	if n.Data == "text" {
+		return nil
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if err := walkTransformed(child, t, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AncestorTransform computes the combined transform of
+// all of the ancestors of an element, not including the
+// element itself.
+func AncestorTransform(n *html.Node) (*Transform, error) {
+	res := Identity()
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type != html.ElementNode {
+			continue
+		}
+		t, err := ParseTransform(scrape.Attr(p, "transform"))
+		if err != nil {
+			return nil, err
+		}
+		res = t.Mul(res)
+	}
+	return res, nil
+}
+
+func shapePolygons(n *html.Node, t *Transform) ([]optishop.Polygon, error) {
+	switch n.Data {
+	case "path":
+		return ParsePath(scrape.Attr(n, "d"), t)
+	case "polygon", "polyline":
+		nums, err := parseNumbers(scrape.Attr(n, "points"))
+		if err != nil {
+			return nil, err
+		} else if len(nums)%2 != 0 {
+			return nil, errors.New("odd number of coordinates in points")
+		}
+		var poly optishop.Polygon
+		for i := 0; i < len(nums); i += 2 {
+			poly = append(poly, optishop.Point{X: nums[i], Y: nums[i+1]})
+		}
+		return finishPolygon(nil, poly, t), nil
+	case "rect":
+		var vals [4]float64
+		for i, attr := range []string{"x", "y", "width", "height"} {
+			x, err := coordAttr(n, attr)
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = x
+		}
+		x, y, w, h := vals[0], vals[1], vals[2], vals[3]
+		return []optishop.Polygon{{
+			t.Apply(optishop.Point{X: x, Y: y}),
+			t.Apply(optishop.Point{X: x + w, Y: y}),
+			t.Apply(optishop.Point{X: x + w, Y: y + h}),
+			t.Apply(optishop.Point{X: x, Y: y + h}),
+		}}, nil
+	}
+	return nil, nil
+}
+
+// coordAttr parses a numerical attribute, which defaults
+// to 0 if it is missing.
+//
+// For attributes with lists of values, like the x of a
+// text element, the first value is used.
+func coordAttr(n *html.Node, name string) (float64, error) {
+	nums, err := parseNumbers(strings.TrimSuffix(strings.TrimSpace(scrape.Attr(n, name)), "px"))
+	if err != nil {
+		return 0, errors.Wrap(err, "attribute "+name)
+	} else if len(nums) == 0 {
+		return 0, nil
+	}
+	return nums[0], nil
+}
+
+// ParsePath converts the subpaths of SVG path data into
+// polygons.
+//
+// Curves are approximated by straight lines between their
+// endpoints.
+func ParsePath(data string, t *Transform) ([]optishop.Polygon, error) {
+	tokens, err := tokenizePath(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []optishop.Polygon
+	var poly optishop.Polygon
+	var cur, start optishop.Point
+	var cmd byte
+	for i := 0; i < len(tokens); {
+		if tokens[i].IsCommand {
+			cmd = tokens[i].Command
+			i++
+			if cmd == 'Z' || cmd == 'z' {
+				results = finishPolygon(results, poly, t)
+				poly = optishop.Polygon{start}
+				cur = start
+				continue
+			}
+		} else if cmd == 0 {
+			return nil, errors.New("path data must start with a command")
+		}
+
+		numArgs, ok := pathArgCounts[cmd]
+		if !ok {
+			return nil, errors.New("unsupported path command: " + string(cmd))
+		}
+		if i+numArgs > len(tokens) {
+			return nil, errors.New("missing arguments for path command: " + string(cmd))
+		}
+		args := make([]float64, numArgs)
+		for j := range args {
+			if tokens[i+j].IsCommand {
+				return nil, errors.New("missing arguments for path command: " + string(cmd))
+			}
+			args[j] = tokens[i+j].Value
+		}
+		i += numArgs
+
+		relative := cmd >= 'a' && cmd <= 'z'
+		var next optishop.Point
+		switch cmd {
+		case 'H', 'h':
+			next = optishop.Point{X: args[0], Y: cur.Y}
+			if relative {
+				next.X += cur.X
+			}
+		case 'V', 'v':
+			next = optishop.Point{X: cur.X, Y: args[0]}
+			if relative {
+				next.Y += cur.Y
+			}
+		default:
+			next = optishop.Point{X: args[numArgs-2], Y: args[numArgs-1]}
+			if relative {
+				next.X += cur.X
+				next.Y += cur.Y
+			}
+		}
+		cur = next
+
+		if cmd == 'M' || cmd == 'm' {
+			results = finishPolygon(results, poly, t)
+			poly = optishop.Polygon{cur}
+			start = cur
+			// Subsequent coordinates are implicit lines.
+			if relative {
+				cmd = 'l'
+			} else {
+				cmd = 'L'
+			}
+		} else {
+			poly = append(poly, cur)
+		}
+	}
+	return finishPolygon(results, poly, t), nil
+}
+
+var pathArgCounts = map[byte]int{
+	'M': 2, 'L': 2, 'T': 2, 'H': 1, 'V': 1, 'C': 6, 'S': 4, 'Q': 4, 'A': 7,
+	'm': 2, 'l': 2, 't': 2, 'h': 1, 'v': 1, 'c': 6, 's': 4, 'q': 4, 'a': 7,
+}
+
+// finishPolygon transforms a polygon and adds it to a list
+// if it has enough distinct points.
+func finishPolygon(polys []optishop.Polygon, poly optishop.Polygon,
+	t *Transform) []optishop.Polygon {
+	if len(poly) > 1 && poly[0] == poly[len(poly)-1] {
+		poly = poly[:len(poly)-1]
+	}
+	if len(poly) < 3 {
+		return polys
+	}
+	res := make(optishop.Polygon, len(poly))
+	for i, p := range poly {
+		res[i] = t.Apply(p)
+	}
+	return append(polys, res)
+}
+
+type pathToken struct {
+	IsCommand bool
+	Command   byte
+	Value     float64
+}
+
+func tokenizePath(data string) ([]pathToken, error) {
+	var res []pathToken
+	for i := 0; i < len(data); {
+		c := data[i]
+		if c == ' ' || c == ',' || c == '\t' || c == '\n' || c == '\r' {
+			i++
+		} else if (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') && c != 'e' && c != 'E' {
+			res = append(res, pathToken{IsCommand: true, Command: c})
+			i++
+		} else {
+			n := numberLength(data[i:])
+			if n == 0 {
+				return nil, errors.New("unexpected character in path data: " + string(c))
+			}
+			x, err := strconv.ParseFloat(data[i:i+n], 64)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, pathToken{Value: x})
+			i += n
+		}
+	}
+	return res, nil
+}
+
+// numberLength finds the length of the number at the
+// start of s, which may be immediately followed by another
+// number (e.g. "1.5.5" or "1-2").
+func numberLength(s string) int {
+	i := 0
+	if i < len(s) && (s[i] == '-' || s[i] == '+') {
+		i++
+	}
+	digits := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		j := i + 1
+		if j < len(s) && (s[j] == '-' || s[j] == '+') {
+			j++
+		}
+		if j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			i = j
+		}
+	}
+	return i
+}
+
+func parseNumbers(s string) ([]float64, error) {
+	tokens, err := tokenizePath(s)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]float64, len(tokens))
+	for i, token := range tokens {
+		if token.IsCommand {
+			return nil, errors.New("unexpected character in numbers: " + string(token.Command))
+		}
+		res[i] = token.Value
+	}
+	return res, nil
+}
+
+// A Transform is a 2-D transformation matrix in the order
+// defined in the SVG spec.
+type Transform [6]float64
+
+// Identity creates a transform that does nothing.
+func Identity() *Transform {
+	return &Transform{1, 0, 0, 1, 0, 0}
+}
+
+// ParseTransform parses an SVG transform attribute, which
+// may be a list of matrix, translate, scale, rotate, skewX,
+// and skewY transformations.
+//
+// An empty transform is the identity.
+func ParseTransform(transform string) (*Transform, error) {
+	res := Identity()
+	rest := strings.TrimSpace(transform)
+	for rest != "" {
+		open := strings.Index(rest, "(")
+		closeIdx := strings.Index(rest, ")")
+		if open < 0 || closeIdx < open {
+			return nil, errors.New("unsupported transform: " + transform)
+		}
+		name := strings.TrimSpace(rest[:open])
+		args, err := parseNumbers(rest[open+1 : closeIdx])
+		if err != nil {
+			return nil, errors.New("unexpected term in transform: " + transform)
+		}
+		t, err := namedTransform(name, args)
+		if err != nil {
+			return nil, errors.Wrap(err, "parse transform "+transform)
+		}
+		res = res.Mul(t)
+		rest = strings.TrimLeft(rest[closeIdx+1:], " \t\n\r,")
+	}
+	return res, nil
+}
+
+func namedTransform(name string, args []float64) (*Transform, error) {
+	badArgs := errors.New("unexpected term count for " + name)
+	switch name {
+	case "matrix":
+		if len(args) != 6 {
+			return nil, badArgs
+		}
+		var res Transform
+		copy(res[:], args)
+		return &res, nil
+	case "translate":
+		if len(args) == 1 {
+			return &Transform{1, 0, 0, 1, args[0], 0}, nil
+		} else if len(args) == 2 {
+			return &Transform{1, 0, 0, 1, args[0], args[1]}, nil
+		}
+		return nil, badArgs
+	case "scale":
+		if len(args) == 1 {
+			return &Transform{args[0], 0, 0, args[0], 0, 0}, nil
+		} else if len(args) == 2 {
+			return &Transform{args[0], 0, 0, args[1], 0, 0}, nil
+		}
+		return nil, badArgs
+	case "rotate":
+		if len(args) != 1 && len(args) != 3 {
+			return nil, badArgs
+		}
+		angle := args[0] * math.Pi / 180
+		cos, sin := math.Cos(angle), math.Sin(angle)
+		rotation := &Transform{cos, sin, -sin, cos, 0, 0}
+		if len(args) == 1 {
+			return rotation, nil
+		}
+		// Rotate about the point (args[1], args[2]).
+		return (&Transform{1, 0, 0, 1, args[1], args[2]}).Mul(rotation).Mul(
+			&Transform{1, 0, 0, 1, -args[1], -args[2]}), nil
+	case "skewX":
+		if len(args) != 1 {
+			return nil, badArgs
+		}
+		return &Transform{1, 0, math.Tan(args[0] * math.Pi / 180), 1, 0, 0}, nil
+	case "skewY":
+		if len(args) != 1 {
+			return nil, badArgs
+		}
+		return &Transform{1, math.Tan(args[0] * math.Pi / 180), 0, 1, 0, 0}, nil
+	}
+	return nil, errors.New("unsupported transform type: " + name)
+}
+
+// Apply transforms a point.
+func (t *Transform) Apply(p optishop.Point) optishop.Point {
+	return optishop.Point{
+		X: p.X*t[0] + p.Y*t[2] + t[4],
+		Y: p.X*t[1] + p.Y*t[3] + t[5],
+	}
+}
+
+// Mul creates a transform which applies t1 and then t.
+func (t *Transform) Mul(t1 *Transform) *Transform {
+	return &Transform{
+		t[0]*t1[0] + t[2]*t1[1],
+		t[1]*t1[0] + t[3]*t1[1],
+		t[0]*t1[2] + t[2]*t1[3],
+		t[1]*t1[2] + t[3]*t1[3],
+		t[0]*t1[4] + t[2]*t1[5] + t[4],
+		t[1]*t1[4] + t[3]*t1[5] + t[5],
+	}
+}
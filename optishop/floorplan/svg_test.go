@@ -0,0 +1,100 @@
+package floorplan
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+func TestParsePath(t *testing.T) {
+	polys, err := ParsePath("M0,0 h10 v10 H0 Z m20-10 l5 0 0 5-5 0z M1.5.5 L2 2", Identity())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []optishop.Polygon{
+		{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}},
+		{{X: 20, Y: -10}, {X: 25, Y: -10}, {X: 25, Y: -5}, {X: 20, Y: -5}},
+	}
+	if len(polys) != len(expected) {
+		t.Fatalf("expected %d polygons but got %v", len(expected), polys)
+	}
+	for i, poly := range polys {
+		if !polygonsClose(poly, expected[i]) {
+			t.Errorf("polygon %d: expected %v but got %v", i, expected[i], poly)
+		}
+	}
+}
+
+func TestParseTransform(t *testing.T) {
+	transform, err := ParseTransform("translate(10, 20) scale(2) rotate(90)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := transform.Apply(optishop.Point{X: 1, Y: 0})
+	if math.Abs(actual.X-10) > 1e-8 || math.Abs(actual.Y-22) > 1e-8 {
+		t.Errorf("unexpected point: %v", actual)
+	}
+	if _, err := ParseTransform("perspective(3)"); err == nil {
+		t.Error("expected error for unsupported transform")
+	}
+}
+
+func TestParseSVG(t *testing.T) {
+	data := []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100">
+  <g transform="translate(10 0)">
+    <g id="walls">
+      <rect x="0" y="0" width="1" height="1" />
+      <path d="M0 0 L50 0 L50 40 L0 40 Z" />
+    </g>
+    <g id="shelves" transform="scale(2)">
+      <polygon points="5,5 10,5 10,10 5,10" />
+    </g>
+    <g id="labels">
+      <text x="30" y="30">A1</text>
+      <text transform="matrix(1 0 0 1 5 35)"><tspan>Entrance</tspan></text>
+    </g>
+  </g>
+</svg>`)
+	floor, err := ParseSVG(data, &DefaultLayers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedBounds := optishop.Polygon{{X: 10, Y: 0}, {X: 60, Y: 0}, {X: 60, Y: 40}, {X: 10, Y: 40}}
+	if !polygonsClose(floor.Bounds, expectedBounds) {
+		t.Errorf("unexpected bounds: %v", floor.Bounds)
+	}
+	expectedShelf := optishop.Polygon{{X: 20, Y: 10}, {X: 30, Y: 10}, {X: 30, Y: 20}, {X: 20, Y: 20}}
+	if len(floor.Obstacles) != 1 || !polygonsClose(floor.Obstacles[0], expectedShelf) {
+		t.Errorf("unexpected obstacles: %v", floor.Obstacles)
+	}
+	if len(floor.NonPreferred) != 0 {
+		t.Errorf("unexpected pads: %v", floor.NonPreferred)
+	}
+	if len(floor.Zones) != 2 {
+		t.Fatalf("unexpected zones: %v", floor.Zones)
+	}
+	aisle, entrance := floor.Zones[0], floor.Zones[1]
+	if aisle.Name != "A1" || !aisle.Specific || aisle.Location != (optishop.Point{X: 40, Y: 30}) {
+		t.Errorf("unexpected aisle: %v", aisle)
+	}
+	if !entrance.Entrance || entrance.Location != (optishop.Point{X: 15, Y: 35}) {
+		t.Errorf("unexpected entrance: %v", entrance)
+	}
+
+	if _, err := ParseSVG(data, &Layers{Walls: "missing"}); err == nil {
+		t.Error("expected error for missing walls")
+	}
+}
+
+func polygonsClose(p1, p2 optishop.Polygon) bool {
+	if len(p1) != len(p2) {
+		return false
+	}
+	for i, p := range p1 {
+		if p.Distance(p2[i]) > 1e-8 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,133 @@
+package handmade
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+// A Product is an entry in a store's catalog.
+type Product struct {
+	ProductName        string `json:"name"`
+	ProductPrice       string `json:"price"`
+	ProductDescription string `json:"description,omitempty"`
+	ProductPhotoURL    string `json:"photoUrl,omitempty"`
+	OutOfStock         bool   `json:"outOfStock,omitempty"`
+
+	// Zone is the name of the zone in the layout where the
+	// product is found. If empty, the location is unknown.
+	Zone string `json:"zone"`
+}
+
+func (p *Product) Name() string {
+	return p.ProductName
+}
+
+func (p *Product) PhotoURL() string {
+	return p.ProductPhotoURL
+}
+
+func (p *Product) Description() string {
+	return p.ProductDescription
+}
+
+func (p *Product) InStock() bool {
+	return !p.OutOfStock
+}
+
+func (p *Product) Price() string {
+	return p.ProductPrice
+}
+
+// A Store is a store with a hand-authored layout and
+// catalog.
+type Store struct {
+	Info         *StoreInfo
+	CachedLayout *optishop.Layout
+	Catalog      []*Product
+
+	layoutLock sync.RWMutex
+}
+
+// Search finds the products in the catalog whose name or
+// description contains every word of the query.
+//
+// If there are no results, the names of products matching
+// any word of the query are suggested.
+func (s *Store) Search(query string) ([]optishop.InventoryProduct, []string, error) {
+	var results []optishop.InventoryProduct
+	for _, product := range s.Catalog {
+		if matchesQuery(query, product.ProductName, product.ProductDescription) {
+			results = append(results, product)
+		}
+	}
+	if len(results) > 0 {
+		return results, nil, nil
+	}
+
+	var suggestions []string
+	for _, product := range s.Catalog {
+		for _, word := range strings.Fields(query) {
+			if matchesQuery(word, product.ProductName) {
+				suggestions = append(suggestions, product.ProductName)
+				break
+			}
+		}
+	}
+	return nil, suggestions, nil
+}
+
+func (s *Store) MarshalProduct(prod optishop.InventoryProduct) ([]byte, error) {
+	return json.Marshal(prod)
+}
+
+func (s *Store) UnmarshalProduct(data []byte) (optishop.InventoryProduct, error) {
+	var prod Product
+	if err := json.Unmarshal(data, &prod); err != nil {
+		return nil, errors.Wrap(err, "unmarshal product")
+	}
+	return &prod, nil
+}
+
+func (s *Store) Layout() *optishop.Layout {
+	s.layoutLock.RLock()
+	defer s.layoutLock.RUnlock()
+	return s.CachedLayout
+}
+
+// SetLayout replaces the layout of the store.
+//
+// It is safe to call while other Goroutines use the store.
+func (s *Store) SetLayout(layout *optishop.Layout) {
+	s.layoutLock.Lock()
+	defer s.layoutLock.Unlock()
+	s.CachedLayout = layout
+}
+
+func (s *Store) Locate(prod optishop.InventoryProduct) (*optishop.Zone, error) {
+	product, ok := prod.(*Product)
+	if !ok {
+		return nil, errors.New("locate product: unexpected product type")
+	}
+	if product.Zone == "" {
+		return nil, nil
+	}
+	zone := s.Layout().Zone(product.Zone)
+	if zone == nil {
+		return nil, errors.New("locate product: zone " + product.Zone + " is missing from the map")
+	}
+	return zone, nil
+}
+
+// MetersPerUnit gets the scale of the store's layout,
+// estimating it from the aisles if the store does not
+// specify it.
+func (s *Store) MetersPerUnit() float64 {
+	if s.Info.MetersPerUnit != 0 {
+		return s.Info.MetersPerUnit
+	}
+	return optishop.EstimateScale(s.Layout(), optishop.DefaultAisleSpacing)
+}
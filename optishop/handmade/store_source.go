@@ -0,0 +1,174 @@
+// Package handmade implements a store source for layouts
+// and product catalogs which are authored by hand.
+//
+// A store source is backed by a directory containing a
+// stores.json file, which lists StoreInfo objects, and two
+// files for every store ID: <ID>.layout.json, containing
+// an optishop.Layout, and <ID>.catalog.json, containing a
+// list of Products.
+package handmade
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+const (
+	storesFile     = "stores.json"
+	layoutSuffix   = ".layout.json"
+	catalogSuffix  = ".catalog.json"
+	earthRadiusKm  = 6371.0
+	maxNearbyCount = 10
+)
+
+// StoreInfo describes a store in a StoreSource.
+type StoreInfo struct {
+	// ID determines the names of the layout and catalog
+	// files for the store.
+	ID string
+
+	StoreName    string
+	StoreAddress string
+	Latitude     float64
+	Longitude    float64
+
+	// MetersPerUnit is the scale of the layout.
+	// If 0, the scale is estimated from the layout.
+	MetersPerUnit float64
+}
+
+func (s *StoreInfo) Name() string {
+	return s.StoreName
+}
+
+func (s *StoreInfo) Address() string {
+	return s.StoreAddress
+}
+
+// A StoreSource provides stores from a directory of
+// hand-authored layouts and catalogs.
+type StoreSource struct {
+	Dir string
+}
+
+// StoreInfos reads the stores listed in the directory.
+func (s *StoreSource) StoreInfos() ([]*StoreInfo, error) {
+	data, err := ioutil.ReadFile(filepath.Join(s.Dir, storesFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "read store infos")
+	}
+	var infos []*StoreInfo
+	if err := json.Unmarshal(data, &infos); err != nil {
+		return nil, errors.Wrap(err, "read store infos")
+	}
+	return infos, nil
+}
+
+func (s *StoreSource) StoresNear(lat, lon float64) ([]optishop.StoreDesc, error) {
+	infos, err := s.StoreInfos()
+	if err != nil {
+		return nil, errors.Wrap(err, "stores near")
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		return haversineDistance(lat, lon, infos[i].Latitude, infos[i].Longitude) <
+			haversineDistance(lat, lon, infos[j].Latitude, infos[j].Longitude)
+	})
+	if len(infos) > maxNearbyCount {
+		infos = infos[:maxNearbyCount]
+	}
+	return storeDescs(infos), nil
+}
+
+func (s *StoreSource) QueryStores(query string) ([]optishop.StoreDesc, error) {
+	infos, err := s.StoreInfos()
+	if err != nil {
+		return nil, errors.Wrap(err, "query stores")
+	}
+	var matches []*StoreInfo
+	for _, info := range infos {
+		if matchesQuery(query, info.StoreName, info.StoreAddress) {
+			matches = append(matches, info)
+		}
+	}
+	return storeDescs(matches), nil
+}
+
+func (s *StoreSource) Store(desc optishop.StoreDesc) (optishop.Store, error) {
+	info, ok := desc.(*StoreInfo)
+	if !ok {
+		return nil, errors.New("load store: unexpected store description")
+	}
+	if info.ID == "" || strings.ContainsAny(info.ID, `/\`) {
+		return nil, errors.New("load store: invalid store ID")
+	}
+
+	var layout optishop.Layout
+	if err := readJSONFile(filepath.Join(s.Dir, info.ID+layoutSuffix), &layout); err != nil {
+		return nil, errors.Wrap(err, "load store")
+	}
+	var catalog []*Product
+	if err := readJSONFile(filepath.Join(s.Dir, info.ID+catalogSuffix), &catalog); err != nil {
+		return nil, errors.Wrap(err, "load store")
+	}
+	return &Store{
+		Info:         info,
+		CachedLayout: &layout,
+		Catalog:      catalog,
+	}, nil
+}
+
+func (s *StoreSource) MarshalStoreDesc(desc optishop.StoreDesc) ([]byte, error) {
+	return json.Marshal(desc)
+}
+
+func (s *StoreSource) UnmarshalStoreDesc(data []byte) (optishop.StoreDesc, error) {
+	var res StoreInfo
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, errors.Wrap(err, "unmarshal store description")
+	}
+	return &res, nil
+}
+
+func storeDescs(infos []*StoreInfo) []optishop.StoreDesc {
+	res := make([]optishop.StoreDesc, len(infos))
+	for i, info := range infos {
+		res[i] = info
+	}
+	return res
+}
+
+// matchesQuery checks if every word of a query appears in
+// at least one of the fields, ignoring case.
+func matchesQuery(query string, fields ...string) bool {
+	text := strings.ToLower(strings.Join(fields, " "))
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func haversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+	a := math.Pow(math.Sin(dLat/2), 2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Pow(math.Sin(dLon/2), 2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+func readJSONFile(path string, obj interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, obj)
+}
@@ -0,0 +1,106 @@
+package handmade
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+func TestStoreSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeJSON(t, filepath.Join(dir, "stores.json"), []*StoreInfo{
+		{ID: "north", StoreName: "North Market", StoreAddress: "1 Main St",
+			Latitude: 42.4, Longitude: -71.1},
+		{ID: "south", StoreName: "South Grocer", StoreAddress: "9 Elm St",
+			Latitude: 42.3, Longitude: -71.1},
+	})
+	writeJSON(t, filepath.Join(dir, "south.layout.json"), &optishop.Layout{
+		Floors: []*optishop.Floor{
+			{
+				Bounds: optishop.Polygon{
+					{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10},
+				},
+				Zones: []*optishop.Zone{
+					{Name: "A1", Location: optishop.Point{X: 2, Y: 2}, Specific: true},
+				},
+			},
+		},
+	})
+	writeJSON(t, filepath.Join(dir, "south.catalog.json"), []*Product{
+		{ProductName: "Whole Milk", ProductPrice: "$3.00", Zone: "A1"},
+		{ProductName: "Oat Milk", ProductPrice: "$4.00"},
+	})
+
+	source := &StoreSource{Dir: dir}
+
+	descs, err := source.QueryStores("grocer")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(descs) != 1 || descs[0].Name() != "South Grocer" {
+		t.Fatalf("unexpected query results: %v", descs)
+	}
+	near, err := source.StoresNear(42.29, -71.1)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(near) != 2 || near[0].Name() != "South Grocer" {
+		t.Fatalf("unexpected nearby stores: %v", near)
+	}
+
+	data, err := source.MarshalStoreDesc(descs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc, err := source.UnmarshalStoreDesc(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := source.Store(desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	products, _, err := store.Search("milk whole")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(products) != 1 || products[0].Name() != "Whole Milk" {
+		t.Fatalf("unexpected search results: %v", products)
+	}
+	if zone, err := store.Locate(products[0]); err != nil {
+		t.Fatal(err)
+	} else if zone == nil || zone.Name != "A1" {
+		t.Errorf("unexpected zone: %v", zone)
+	}
+
+	products, _, err = store.Search("oat")
+	if err != nil {
+		t.Fatal(err)
+	} else if zone, err := store.Locate(products[0]); err != nil || zone != nil {
+		t.Errorf("expected unknown location but got %v, %v", zone, err)
+	}
+
+	_, suggestions, err := store.Search("skim milk")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(suggestions) != 2 {
+		t.Errorf("unexpected suggestions: %v", suggestions)
+	}
+}
+
+func writeJSON(t *testing.T, path string, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,5 +1,7 @@
 package optishop
 
+import "sort"
+
 // A PortalType is a way of getting from one floor to
 // another in a store.
 type PortalType string
@@ -7,6 +9,7 @@ type PortalType string
 const (
 	Elevator  PortalType = "elevator"
 	Escalator PortalType = "escalator"
+	Stairs    PortalType = "stairs"
 )
 
 // A Layout specifies the physical layout of a store.
@@ -124,6 +127,51 @@ func (f *Floor) Zone(name string) *Zone {
 	return nil
 }
 
+// NearestZone finds the named zone closest to a point, or
+// returns nil if the floor has no named zones.
+func (f *Floor) NearestZone(p Point) *Zone {
+	zones := f.NearestZones(p, 1)
+	if len(zones) == 0 {
+		return nil
+	}
+	return zones[0]
+}
+
+// NearestZones finds up to n named zones, sorted from
+// closest to farthest from a point.
+func (f *Floor) NearestZones(p Point, n int) []*Zone {
+	var zones []*Zone
+	for _, z := range f.Zones {
+		if z.Name != "" {
+			zones = append(zones, z)
+		}
+	}
+	sort.SliceStable(zones, func(i, j int) bool {
+		return zones[i].Location.Distance(p) < zones[j].Location.Distance(p)
+	})
+	if len(zones) > n {
+		zones = zones[:n]
+	}
+	return zones
+}
+
+// Contains checks if a point is within the bounds of the
+// floor.
+func (f *Floor) Contains(p Point) bool {
+	return NewPolyContainer(f.Bounds).Contains(p)
+}
+
+// InObstacle checks if a point is inside of any obstacle
+// on the floor.
+func (f *Floor) InObstacle(p Point) bool {
+	for _, obstacle := range f.Obstacles {
+		if NewPolyContainer(obstacle).Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
 // A Zone is an arbitrary location in a store.
 type Zone struct {
 	// If not empty, may be an aisle name or a department
@@ -0,0 +1,320 @@
+package optishop
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// A LayoutEditType is a kind of correction to a Layout.
+type LayoutEditType string
+
+const (
+	AddZone               LayoutEditType = "add_zone"
+	MoveZone              LayoutEditType = "move_zone"
+	RenameZone            LayoutEditType = "rename_zone"
+	RemoveZone            LayoutEditType = "remove_zone"
+	AddObstacle           LayoutEditType = "add_obstacle"
+	SetPortalDestinations LayoutEditType = "set_portal_destinations"
+)
+
+// A LayoutEdit is a correction to a Layout, such as
+// renaming a mislabeled aisle.
+//
+// Which fields are used depends on the Type.
+type LayoutEdit struct {
+	Type LayoutEditType `json:"type"`
+
+	// Floor is the floor index for AddZone and AddObstacle.
+	Floor int `json:"floor"`
+
+	// ZoneName is the name of the existing zone for
+	// MoveZone, RenameZone, and RemoveZone.
+	ZoneName string `json:"zoneName"`
+
+	// Zone is the new zone for AddZone.
+	Zone *Zone `json:"zone"`
+
+	// Location is the new location for MoveZone.
+	Location Point `json:"location"`
+
+	// NewName is the new name for RenameZone.
+	NewName string `json:"newName"`
+
+	// Obstacle is the new obstacle for AddObstacle.
+	Obstacle Polygon `json:"obstacle"`
+
+	// PortalID and Destinations are used for
+	// SetPortalDestinations.
+	PortalID     int   `json:"portalId"`
+	Destinations []int `json:"destinations"`
+}
+
+// Apply performs the edit on a layout in place.
+func (l *LayoutEdit) Apply(layout *Layout) error {
+	switch l.Type {
+	case AddZone:
+		if l.Zone == nil {
+			return errors.New("apply layout edit: missing zone")
+		}
+		floor, err := l.floor(layout)
+		if err != nil {
+			return err
+		}
+		if l.Zone.Name != "" && layout.Zone(l.Zone.Name) != nil {
+			return errors.New("apply layout edit: zone already exists: " + l.Zone.Name)
+		}
+		zone := *l.Zone
+		floor.Zones = append(floor.Zones, &zone)
+	case MoveZone:
+		zone, err := l.zone(layout)
+		if err != nil {
+			return err
+		}
+		zone.Location = l.Location
+	case RenameZone:
+		zone, err := l.zone(layout)
+		if err != nil {
+			return err
+		}
+		if l.NewName != "" && layout.Zone(l.NewName) != nil {
+			return errors.New("apply layout edit: zone already exists: " + l.NewName)
+		}
+		zone.Name = l.NewName
+	case RemoveZone:
+		zone, err := l.zone(layout)
+		if err != nil {
+			return err
+		}
+		floor := layout.Floors[layout.ZoneFloor(zone)]
+		for i, z := range floor.Zones {
+			if z == zone {
+				floor.Zones = append(floor.Zones[:i], floor.Zones[i+1:]...)
+				break
+			}
+		}
+	case AddObstacle:
+		floor, err := l.floor(layout)
+		if err != nil {
+			return err
+		}
+		if len(l.Obstacle) < 3 {
+			return errors.New("apply layout edit: obstacle needs at least three points")
+		}
+		floor.Obstacles = append(floor.Obstacles, append(Polygon{}, l.Obstacle...))
+	case SetPortalDestinations:
+		portal := layout.Portal(l.PortalID)
+		if portal == nil {
+			return errors.New("apply layout edit: no such portal: " + strconv.Itoa(l.PortalID))
+		}
+		for _, dest := range l.Destinations {
+			if layout.Portal(dest) == nil {
+				return errors.New("apply layout edit: no such portal: " + strconv.Itoa(dest))
+			}
+		}
+		portal.Destinations = append([]int{}, l.Destinations...)
+	default:
+		return errors.New("apply layout edit: unknown edit type: " + string(l.Type))
+	}
+	return nil
+}
+
+// String describes the edit in a human-readable way.
+func (l *LayoutEdit) String() string {
+	switch l.Type {
+	case AddZone:
+		if l.Zone == nil {
+			return "add zone"
+		}
+		return fmt.Sprintf("add zone %q on floor %d at (%.2f, %.2f)", l.Zone.Name, l.Floor,
+			l.Zone.Location.X, l.Zone.Location.Y)
+	case MoveZone:
+		return fmt.Sprintf("move zone %q to (%.2f, %.2f)", l.ZoneName, l.Location.X, l.Location.Y)
+	case RenameZone:
+		return fmt.Sprintf("rename zone %q to %q", l.ZoneName, l.NewName)
+	case RemoveZone:
+		return fmt.Sprintf("remove zone %q", l.ZoneName)
+	case AddObstacle:
+		return fmt.Sprintf("add obstacle with %d points on floor %d", len(l.Obstacle), l.Floor)
+	case SetPortalDestinations:
+		return fmt.Sprintf("link portal %d to portals %v", l.PortalID, l.Destinations)
+	}
+	return "unknown edit " + string(l.Type)
+}
+
+func (l *LayoutEdit) floor(layout *Layout) (*Floor, error) {
+	if l.Floor < 0 || l.Floor >= len(layout.Floors) {
+		return nil, errors.New("apply layout edit: no such floor: " + strconv.Itoa(l.Floor))
+	}
+	return layout.Floors[l.Floor], nil
+}
+
+func (l *LayoutEdit) zone(layout *Layout) (*Zone, error) {
+	zone := layout.Zone(l.ZoneName)
+	if zone == nil {
+		return nil, errors.New("apply layout edit: no such zone: " + l.ZoneName)
+	}
+	return zone, nil
+}
+
+// ApplyLayoutEdits creates a copy of a layout with a
+// sequence of edits applied to it.
+func ApplyLayoutEdits(layout *Layout, edits []*LayoutEdit) (*Layout, error) {
+	res := layout.Copy()
+	for i, edit := range edits {
+		if err := edit.Apply(res); err != nil {
+			return nil, errors.Wrap(err, "edit "+strconv.Itoa(i))
+		}
+	}
+	return res, nil
+}
+
+// Copy creates a deep copy of the layout.
+func (l *Layout) Copy() *Layout {
+	res := &Layout{Floors: make([]*Floor, len(l.Floors))}
+	for i, f := range l.Floors {
+		floor := &Floor{
+			Name:   f.Name,
+			Bounds: append(Polygon{}, f.Bounds...),
+		}
+		for _, z := range f.Zones {
+			zone := *z
+			floor.Zones = append(floor.Zones, &zone)
+		}
+		for _, p := range f.Portals {
+			portal := *p
+			portal.Destinations = append([]int{}, p.Destinations...)
+			floor.Portals = append(floor.Portals, &portal)
+		}
+		for _, o := range f.Obstacles {
+			floor.Obstacles = append(floor.Obstacles, append(Polygon{}, o...))
+		}
+		for _, np := range f.NonPreferred {
+			floor.NonPreferred = append(floor.NonPreferred, &NonPreferred{
+				Bounds:  append(Polygon{}, np.Bounds...),
+				Visible: np.Visible,
+			})
+		}
+		res.Floors[i] = floor
+	}
+	return res
+}
+
+// A LayoutChange is a difference between two layouts.
+type LayoutChange struct {
+	Floor int
+
+	// Location is the affected point in the new layout, or
+	// in the old layout if the point was removed.
+	Location *Point
+
+	Message string
+}
+
+// String formats the change along with its location.
+func (l *LayoutChange) String() string {
+	problem := LayoutProblem{Floor: l.Floor, Location: l.Location, Message: l.Message}
+	return problem.String()
+}
+
+// DiffLayouts finds the zones, obstacles, and portal links
+// that differ between an old and a new layout.
+//
+// Zones are matched by name, so a renamed zone appears as
+// a removed zone and an added zone.
+func DiffLayouts(old, new *Layout) []*LayoutChange {
+	var res []*LayoutChange
+	add := func(floor int, p Point, msg string) {
+		res = append(res, &LayoutChange{Floor: floor, Location: &p, Message: msg})
+	}
+
+	numFloors := len(old.Floors)
+	if len(new.Floors) > numFloors {
+		numFloors = len(new.Floors)
+	}
+	for i := 0; i < numFloors; i++ {
+		oldFloor, newFloor := &Floor{}, &Floor{}
+		if i < len(old.Floors) {
+			oldFloor = old.Floors[i]
+		}
+		if i < len(new.Floors) {
+			newFloor = new.Floors[i]
+		}
+
+		for _, z := range oldFloor.Zones {
+			if z.Name == "" {
+				continue
+			}
+			if newZone := newFloor.Zone(z.Name); newZone == nil {
+				add(i, z.Location, "removed "+problemZoneName(z))
+			} else if newZone.Location != z.Location {
+				add(i, newZone.Location, fmt.Sprintf("moved %s from (%.2f, %.2f)",
+					problemZoneName(z), z.Location.X, z.Location.Y))
+			}
+		}
+		for _, z := range newFloor.Zones {
+			if z.Name != "" && oldFloor.Zone(z.Name) == nil {
+				add(i, z.Location, "added "+problemZoneName(z))
+			}
+		}
+
+		for _, o := range oldFloor.Obstacles {
+			if !containsPolygon(newFloor.Obstacles, o) && len(o) > 0 {
+				add(i, o[0], fmt.Sprintf("removed obstacle with %d points", len(o)))
+			}
+		}
+		for _, o := range newFloor.Obstacles {
+			if !containsPolygon(oldFloor.Obstacles, o) && len(o) > 0 {
+				add(i, o[0], fmt.Sprintf("added obstacle with %d points", len(o)))
+			}
+		}
+
+		for _, p := range oldFloor.Portals {
+			if new.Portal(p.ID) == nil {
+				add(i, p.Location, "removed "+problemPortalName(p))
+			}
+		}
+		for _, p := range newFloor.Portals {
+			oldPortal := old.Portal(p.ID)
+			if oldPortal == nil {
+				add(i, p.Location, "added "+problemPortalName(p))
+			} else if !equalInts(oldPortal.Destinations, p.Destinations) {
+				add(i, p.Location, fmt.Sprintf("changed destinations of %s from %v to %v",
+					problemPortalName(p), oldPortal.Destinations, p.Destinations))
+			}
+		}
+	}
+	return res
+}
+
+func containsPolygon(polys []Polygon, poly Polygon) bool {
+	for _, p := range polys {
+		if len(p) != len(poly) {
+			continue
+		}
+		equal := true
+		for i, point := range p {
+			if point != poly[i] {
+				equal = false
+				break
+			}
+		}
+		if equal {
+			return true
+		}
+	}
+	return false
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, x := range a {
+		if b[i] != x {
+			return false
+		}
+	}
+	return true
+}
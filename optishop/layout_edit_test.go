@@ -0,0 +1,95 @@
+package optishop
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestApplyLayoutEdits(t *testing.T) {
+	layout := testValidationLayout()
+	edits := []*LayoutEdit{
+		{Type: RenameZone, ZoneName: "A1", NewName: "A2"},
+		{Type: MoveZone, ZoneName: "B1", Location: Point{X: 6, Y: 6}},
+		{Type: AddZone, Floor: 1, Zone: &Zone{Name: "B2", Location: Point{X: 2, Y: 2}}},
+		{Type: AddObstacle, Floor: 1, Obstacle: Polygon{{X: 7, Y: 1}, {X: 8, Y: 1}, {X: 8, Y: 2}}},
+		{Type: SetPortalDestinations, PortalID: 1, Destinations: []int{}},
+	}
+	edited, err := ApplyLayoutEdits(layout, edits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if layout.Zone("A1") == nil || layout.Zone("B2") != nil {
+		t.Error("original layout was modified")
+	}
+	if edited.Zone("A1") != nil || edited.Zone("A2") == nil {
+		t.Error("zone was not renamed")
+	}
+	if loc := edited.Zone("B1").Location; loc != (Point{X: 6, Y: 6}) {
+		t.Errorf("unexpected location: %v", loc)
+	}
+
+	changes := DiffLayouts(layout, edited)
+	expected := []string{
+		`floor 0 (5.00, 5.00): removed zone "A1"`,
+		`floor 0 (5.00, 5.00): added zone "A2"`,
+		`floor 1 (6.00, 6.00): moved zone "B1" from (5.00, 5.00)`,
+		`floor 1 (2.00, 2.00): added zone "B2"`,
+		`floor 1 (7.00, 1.00): added obstacle with 3 points`,
+		`floor 1 (5.00, 8.00): changed destinations of escalator portal 1 from [0] to []`,
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes but got %v", len(expected), changes)
+	}
+	for i, change := range changes {
+		if change.String() != expected[i] {
+			t.Errorf("change %d: expected %q but got %q", i, expected[i], change.String())
+		}
+	}
+
+	if _, err := ApplyLayoutEdits(layout, []*LayoutEdit{
+		{Type: RemoveZone, ZoneName: "missing"},
+	}); err == nil {
+		t.Error("expected error for missing zone")
+	}
+}
+
+func TestDiffLayoutsRemovedPortal(t *testing.T) {
+	layout := testValidationLayout()
+	edited := layout.Copy()
+	edited.Floors[1].Portals = nil
+
+	changes := DiffLayouts(layout, edited)
+	expected := `floor 1 (5.00, 8.00): removed escalator portal 1`
+	if len(changes) != 1 || changes[0].String() != expected {
+		t.Errorf("expected [%s] but got %v", expected, changes)
+	}
+}
+
+func TestLayoutEditJSON(t *testing.T) {
+	edit := &LayoutEdit{Type: SetPortalDestinations, PortalID: 3, Destinations: []int{1, 2}}
+	data, err := json.Marshal(edit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"type", "zoneName", "newName", "portalId", "destinations"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("missing field %q in %s", name, data)
+		}
+	}
+
+	// Overrides saved before the fields had tags should
+	// still be readable.
+	var decoded LayoutEdit
+	oldData := []byte(`{"Type":"rename_zone","ZoneName":"A1","NewName":"A2"}`)
+	if err := json.Unmarshal(oldData, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Type != RenameZone || decoded.ZoneName != "A1" || decoded.NewName != "A2" {
+		t.Errorf("unexpected edit: %v", decoded.String())
+	}
+}
@@ -0,0 +1,44 @@
+package optishop
+
+import "testing"
+
+func TestFloorNearestZones(t *testing.T) {
+	floor := &Floor{
+		Zones: []*Zone{
+			&Zone{Name: "A1", Location: Point{1, 1}},
+			&Zone{Location: Point{5, 5}},
+			&Zone{Name: "A2", Location: Point{4, 4}},
+			&Zone{Name: "A3", Location: Point{8, 8}},
+		},
+	}
+	actual := floor.NearestZones(Point{5, 5}, 2)
+	if len(actual) != 2 || actual[0].Name != "A2" || actual[1].Name != "A3" {
+		t.Errorf("unexpected zones: %v", actual)
+	}
+	if actual := floor.NearestZones(Point{5, 5}, 10); len(actual) != 3 {
+		t.Errorf("expected 3 zones but got %d", len(actual))
+	}
+	if zone := floor.NearestZone(Point{9, 9}); zone.Name != "A3" {
+		t.Errorf("unexpected nearest zone: %s", zone.Name)
+	}
+}
+
+func TestFloorInObstacle(t *testing.T) {
+	floor := &Floor{
+		Bounds: Polygon{{0, 0}, {10, 0}, {10, 10}, {0, 10}},
+		Obstacles: []Polygon{
+			{{2, 2}, {4, 2}, {4, 4}, {2, 4}},
+		},
+	}
+	points := []Point{{3, 3}, {5, 5}, {11, 5}}
+	inFloor := []bool{true, true, false}
+	inObstacle := []bool{true, false, false}
+	for i, p := range points {
+		if actual := floor.Contains(p); actual != inFloor[i] {
+			t.Errorf("point %v: expected contains %v but got %v", p, inFloor[i], actual)
+		}
+		if actual := floor.InObstacle(p); actual != inObstacle[i] {
+			t.Errorf("point %v: expected in obstacle %v but got %v", p, inObstacle[i], actual)
+		}
+	}
+}
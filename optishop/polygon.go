@@ -49,6 +49,32 @@ func (p Path) Length() float64 {
 	return res
 }
 
+// Simplify removes points from the path which are within
+// epsilon of the line between their neighbors, using the
+// Ramer-Douglas-Peucker algorithm.
+//
+// The first and last points are always kept.
+func (p Path) Simplify(epsilon float64) Path {
+	if len(p) < 3 {
+		return append(Path{}, p...)
+	}
+	maxDist := -1.0
+	maxIdx := 0
+	line := &lineSegment{Start: p[0], End: p[len(p)-1]}
+	for i := 1; i < len(p)-1; i++ {
+		if d := line.Distance(p[i]); d > maxDist {
+			maxDist = d
+			maxIdx = i
+		}
+	}
+	if maxDist <= epsilon {
+		return Path{p[0], p[len(p)-1]}
+	}
+	first := p[:maxIdx+1].Simplify(epsilon)
+	second := p[maxIdx:].Simplify(epsilon)
+	return append(first, second[1:]...)
+}
+
 // A Polygon is an arbitrary closed path.
 // It is obtained by tracing a path from the first point
 // to the last, and then back to the first point again.
@@ -91,6 +117,21 @@ type lineSegment struct {
 	End   Point
 }
 
+// Distance computes the distance from p to the closest
+// point on the line segment.
+func (l *lineSegment) Distance(p Point) float64 {
+	direction := l.End.Sub(l.Start)
+	normSquared := direction.X*direction.X + direction.Y*direction.Y
+	if normSquared == 0 {
+		return p.Distance(l.Start)
+	}
+	offset := p.Sub(l.Start)
+	frac := (offset.X*direction.X + offset.Y*direction.Y) / normSquared
+	frac = math.Max(0, math.Min(1, frac))
+	closest := Point{X: l.Start.X + frac*direction.X, Y: l.Start.Y + frac*direction.Y}
+	return p.Distance(closest)
+}
+
 // A PolyContainer can check if a polygon contains any
 // arbitrary point.
 type PolyContainer struct {
@@ -210,3 +210,20 @@ func randomConvexPoly() ConvexPolygon {
 
 	return res
 }
+
+func TestPathSimplify(t *testing.T) {
+	path := Path{{0, 0}, {1, 0.01}, {2, 0}, {2, 1}, {1.99, 2}, {2, 3}, {0, 3}}
+	actual := path.Simplify(0.1)
+	expected := Path{{0, 0}, {2, 0}, {2, 3}, {0, 3}}
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %v but got %v", expected, actual)
+	}
+	for i, x := range expected {
+		if actual[i] != x {
+			t.Fatalf("expected %v but got %v", expected, actual)
+		}
+	}
+	if len(path.Simplify(0)) != len(path) {
+		t.Error("unexpected removal with zero epsilon")
+	}
+}
@@ -0,0 +1,51 @@
+package optishop
+
+import "math"
+
+// A PortalCost describes how expensive it is to travel
+// through a kind of portal.
+//
+// Costs are measured in the same units as distances
+// within a floor, so that a portal can be compared to the
+// distance a shopper would walk instead.
+type PortalCost struct {
+	// Fixed is added every time the portal is used, e.g.
+	// to account for waiting for an elevator.
+	Fixed float64
+
+	// PerFloor is multiplied by the number of floors that
+	// the portal traverses, e.g. to account for the length
+	// of an escalator or a flight of stairs.
+	PerFloor float64
+}
+
+// Cost computes the cost of using the portal to travel
+// the given number of floors.
+func (p PortalCost) Cost(numFloors int) float64 {
+	return p.Fixed + p.PerFloor*math.Abs(float64(numFloors))
+}
+
+// DefaultPortalCosts creates a reasonable PortalCost for
+// each PortalType.
+//
+// Since layouts do not have a known scale, costs are
+// proportional to the size of the largest floor.
+func DefaultPortalCosts(layout *Layout) map[PortalType]PortalCost {
+	size := LayoutSize(layout)
+	return map[PortalType]PortalCost{
+		Elevator:  PortalCost{Fixed: size * 0.5, PerFloor: size * 0.05},
+		Escalator: PortalCost{PerFloor: size * 0.15},
+		Stairs:    PortalCost{PerFloor: size * 0.25},
+	}
+}
+
+// LayoutSize computes the largest dimension of any floor
+// in the layout.
+func LayoutSize(layout *Layout) float64 {
+	var size float64
+	for _, floor := range layout.Floors {
+		_, _, w, h := floor.Bounds.Bounds()
+		size = math.Max(size, math.Max(w, h))
+	}
+	return size
+}
@@ -0,0 +1,42 @@
+package optishop
+
+// A RoutingProfile restricts the paths that a shopper can
+// take through a store, e.g. for shoppers with strollers
+// or wheelchairs.
+type RoutingProfile struct {
+	// PortalTypes, if non-nil, lists the only types of
+	// portals that may be used to change floors.
+	PortalTypes []PortalType
+
+	// Clearance is the minimum distance that paths must
+	// keep from obstacles and the bounds of a floor.
+	//
+	// This can be used to avoid aisles that are too
+	// narrow for a wheelchair.
+	Clearance float64
+}
+
+// AccessibleProfile creates a RoutingProfile which only
+// uses elevators and keeps the given clearance.
+func AccessibleProfile(clearance float64) *RoutingProfile {
+	return &RoutingProfile{
+		PortalTypes: []PortalType{Elevator},
+		Clearance:   clearance,
+	}
+}
+
+// AllowsPortal checks if the profile allows a type of
+// portal to be used.
+//
+// A nil profile allows every type of portal.
+func (r *RoutingProfile) AllowsPortal(t PortalType) bool {
+	if r == nil || r.PortalTypes == nil {
+		return true
+	}
+	for _, x := range r.PortalTypes {
+		if x == t {
+			return true
+		}
+	}
+	return false
+}
@@ -29,3 +29,15 @@ type Store interface {
 	// Returns nil if the product's location is unknown.
 	Locate(product InventoryProduct) (*Zone, error)
 }
+
+// An EditableStore is a Store whose layout can be
+// replaced, e.g. with a corrected version of it.
+type EditableStore interface {
+	Store
+
+	// SetLayout replaces the layout of the store.
+	//
+	// Afterwards, Locate must return zones from the new
+	// layout.
+	SetLayout(layout *Layout)
+}
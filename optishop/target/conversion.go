@@ -93,6 +93,8 @@ func portalTypeForMarker(m *MapMarker) optishop.PortalType {
 		return optishop.Elevator
 	} else if m.Name == "escalators" {
 		return optishop.Escalator
+	} else if m.Name == "stairs" {
+		return optishop.Stairs
 	} else {
 		return ""
 	}
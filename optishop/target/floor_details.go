@@ -7,6 +7,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/unixpickle/optishop-server/optishop"
+	"github.com/unixpickle/optishop-server/optishop/floorplan"
 	"github.com/yhat/scrape"
 	"golang.org/x/net/html"
 )
@@ -45,7 +46,7 @@ func parseFloorDetails(data []byte) (*FloorDetails, error) {
 	if !ok {
 		return nil, errors.New("missing 'content' group")
 	}
-	transform, err := parseCoordTransform(scrape.Attr(content, "transform"))
+	transform, err := floorplan.ParseTransform(scrape.Attr(content, "transform"))
 	if err != nil {
 		return nil, err
 	}
@@ -56,13 +57,16 @@ func parseFloorDetails(data []byte) (*FloorDetails, error) {
 	// these cases all but one of the shapes tend to just
 	// be a useless tiny rectangle.
 	// See e.g. https://www.target.com/sl/mays-landing/1109.
+	// The bounds are the shape with the largest area, since
+	// a small extra shape may still have more vertices than
+	// a simple rectangular floor.
 	polys, err := pathPolygons(parsed, "Wall-Shapes", transform)
 	if err != nil {
 		return nil, err
 	} else if len(polys) == 0 {
 		return nil, errors.New("invalid bounding shape")
 	}
-	result.Bounds = largestPath(polys)
+	result.Bounds = floorplan.LargestPolygon(polys)
 
 	result.Obstacles, err = pathPolygons(parsed, "Aisle-Shapes", transform)
 	if err != nil {
@@ -78,14 +82,12 @@ func parseFloorDetails(data []byte) (*FloorDetails, error) {
 	if !ok {
 		return nil, errors.New("missing 'Aisle-Names' group")
 	}
-	for _, text := range findTag(aisleNames, "text") {
-		xText := scrape.Attr(text, "x")
-		yText := scrape.Attr(text, "y")
-		x, err := strconv.ParseFloat(xText, 64)
+	for _, text := range floorplan.FindTag(aisleNames, "text") {
+		x, err := strconv.ParseFloat(scrape.Attr(text, "x"), 64)
 		if err != nil {
 			return nil, err
 		}
-		y, err := strconv.ParseFloat(yText, 64)
+		y, err := strconv.ParseFloat(scrape.Attr(text, "y"), 64)
 		if err != nil {
 			return nil, err
 		}
@@ -98,66 +100,22 @@ func parseFloorDetails(data []byte) (*FloorDetails, error) {
 	return result, nil
 }
 
-func findTag(elem *html.Node, tag string) []*html.Node {
-	return scrape.FindAll(elem, func(n *html.Node) bool {
-		return n.Type == html.ElementNode && n.Data == tag
-	})
-}
-
-func largestPath(paths []optishop.Polygon) optishop.Polygon {
-	if len(paths) == 0 {
-		return nil
-	}
-	res := paths[0]
-	for _, p := range paths[1:] {
-		if len(p) > len(res) {
-			res = p
-		}
-	}
-	return res
-}
-
-// A coordTransform is a 2-D transformation matrix in the
-// order defined in the SVG spec.
-type coordTransform [6]float64
-
-func parseCoordTransform(transform string) (*coordTransform, error) {
-	if transform == "" {
-		return &coordTransform{1, 0, 0, 1, 0, 0}, nil
-	}
-	if !strings.HasPrefix(transform, "matrix(") || !strings.HasSuffix(transform, ")") {
-		return nil, errors.New("unsupported transform: " + transform)
-	}
-	comps := strings.Fields(transform[7 : len(transform)-1])
-	if len(comps) != 6 {
-		return nil, errors.New("unexpected term count in transform: " + transform)
-	}
-	var res coordTransform
-	for i, comp := range comps {
-		x, err := strconv.ParseFloat(comp, 64)
-		if err != nil {
-			return nil, errors.New("unexpected term in transform: " + transform)
-		}
-		res[i] = x
-	}
-	return &res, nil
-}
-
-func (c *coordTransform) Apply(p optishop.Point) optishop.Point {
-	return optishop.Point{
-		X: p.X*c[0] + p.Y*c[2] + c[4],
-		Y: p.X*c[1] + p.Y*c[3] + c[5],
-	}
-}
-
-func pathPolygons(container *html.Node, id string, c *coordTransform) ([]optishop.Polygon, error) {
+// pathPolygons finds the polygons of the path elements
+// inside the element with the given ID.
+//
+// Unlike floorplan.PathPolygons, other kinds of shapes and
+// the transforms of nested elements are ignored, since
+// Target's maps only position their content with the
+// transform of the content group.
+func pathPolygons(container *html.Node, id string,
+	t *floorplan.Transform) ([]optishop.Polygon, error) {
 	elem, ok := scrape.Find(container, scrape.ById(id))
 	if !ok {
 		return nil, errors.New("missing '" + id + "' group")
 	}
 	var res []optishop.Polygon
-	for _, path := range findTag(elem, "path") {
-		polys, err := rawPathPolygons(path, c)
+	for _, path := range floorplan.FindTag(elem, "path") {
+		polys, err := floorplan.ParsePath(scrape.Attr(path, "d"), t)
 		if err != nil {
 			return nil, err
 		}
@@ -165,43 +123,3 @@ func pathPolygons(container *html.Node, id string, c *coordTransform) ([]optisho
 	}
 	return res, nil
 }
-
-func rawPathPolygons(elem *html.Node, c *coordTransform) ([]optishop.Polygon, error) {
-	data := scrape.Attr(elem, "d")
-	var results []optishop.Polygon
-	for _, shape := range strings.Split(data, "Z") {
-		if shape == "" {
-			continue
-		}
-		polygon := optishop.Polygon{}
-		fields := strings.Fields(shape)
-		if len(fields) == 0 {
-			return nil, errors.New("empty path")
-		}
-		if len(fields)%2 != 0 {
-			return nil, errors.New("expected even # of fields followed by Z")
-		}
-		for i := 0; i < len(fields)-2; i += 2 {
-			cmd1 := fields[i]
-			cmd2 := fields[i+1]
-			if i == 0 {
-				if !strings.HasPrefix(cmd1, "M") {
-					return nil, errors.New("expected move command")
-				}
-			} else if !strings.HasPrefix(cmd1, "L") {
-				return nil, errors.New("expected line command")
-			}
-			val1, err := strconv.ParseFloat(cmd1[1:], 64)
-			if err != nil {
-				return nil, err
-			}
-			val2, err := strconv.ParseFloat(cmd2, 64)
-			if err != nil {
-				return nil, err
-			}
-			polygon = append(polygon, c.Apply(optishop.Point{X: val1, Y: val2}))
-		}
-		results = append(results, polygon)
-	}
-	return results, nil
-}
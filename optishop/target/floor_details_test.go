@@ -0,0 +1,63 @@
+package target
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+func TestParseFloorDetails(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "floor.svg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := parseFloorDetails(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The expected details were produced by the original
+	// parser, before it was based on the floorplan package.
+	data, err = ioutil.ReadFile(filepath.Join("testdata", "floor_details.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected FloorDetails
+	if err := json.Unmarshal(data, &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(actual.Bounds, expected.Bounds) {
+		t.Errorf("expected bounds %v but got %v", expected.Bounds, actual.Bounds)
+	}
+	if !reflect.DeepEqual(actual.Obstacles, expected.Obstacles) {
+		t.Errorf("expected obstacles %v but got %v", expected.Obstacles, actual.Obstacles)
+	}
+	if !reflect.DeepEqual(actual.FloorPads, expected.FloorPads) {
+		t.Errorf("expected floor pads %v but got %v", expected.FloorPads, actual.FloorPads)
+	}
+	if !reflect.DeepEqual(actual.Aisles, expected.Aisles) {
+		t.Errorf("expected aisles %v but got %v", expected.Aisles, actual.Aisles)
+	}
+}
+
+func TestParseFloorDetailsBounds(t *testing.T) {
+	// The floor is a rectangle, but the tiny extra wall
+	// shape has more vertices.
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "floor_bounds.svg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := parseFloorDetails(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := optishop.Polygon{{X: 0, Y: 0}, {X: 1000, Y: 0}, {X: 1000, Y: 750}, {X: 0, Y: 750}}
+	if !reflect.DeepEqual(actual.Bounds, expected) {
+		t.Errorf("expected bounds %v but got %v", expected, actual.Bounds)
+	}
+}
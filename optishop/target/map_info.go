@@ -53,7 +53,7 @@ type MapMarker struct {
 // Portal checks if the map marker is a way to get between
 // floors of the store.
 func (m *MapMarker) Portal() bool {
-	return m.Name == "elevators" || m.Name == "escalators"
+	return m.Name == "elevators" || m.Name == "escalators" || m.Name == "stairs"
 }
 
 // GetMapInfo looks up general map information for a given
@@ -82,7 +82,7 @@ func GetMapInfo(storeID string) (*MapInfo, error) {
 	return &info, nil
 }
 
-// MatchFloorPortals matches elevators and escalators
+// MatchFloorPortals matches elevators, escalators, and stairs
 // between two consecutive floors, finding a sequence
 // of pairs of {first_portal, second_portal} where the
 // first floor's map marker is matched to the second's.
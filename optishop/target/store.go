@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 	"github.com/unixpickle/optishop-server/optishop"
@@ -114,6 +115,8 @@ type Store struct {
 	StoreID      string
 	Client       *Client
 	CachedLayout *optishop.Layout
+
+	layoutLock sync.RWMutex
 }
 
 func NewStore(storeID string) (*Store, error) {
@@ -199,23 +202,34 @@ func (s *Store) UnmarshalProduct(data []byte) (optishop.InventoryProduct, error)
 }
 
 func (s *Store) Layout() *optishop.Layout {
+	s.layoutLock.RLock()
+	defer s.layoutLock.RUnlock()
 	return s.CachedLayout
 }
 
+// SetLayout replaces the layout of the store.
+//
+// It is safe to call while other Goroutines use the store.
+func (s *Store) SetLayout(layout *optishop.Layout) {
+	s.layoutLock.Lock()
+	defer s.layoutLock.Unlock()
+	s.CachedLayout = layout
+}
+
 func (s *Store) Locate(prod optishop.InventoryProduct) (*optishop.Zone, error) {
 	tcin := prod.(tcinItem).TCIN()
 	if res, err := s.Client.SingleFulfillment(s.StoreID, tcin); err != nil {
 		return nil, errors.Wrap(err, "locate product")
 	} else {
 		name := res.ZoneName()
-		zone := s.CachedLayout.Zone(name)
+		zone := s.Layout().Zone(name)
 		if zone == nil {
 			if v2, ok := prod.(*inventoryProductV2); ok {
 				department, err := ProductDepartment(v2.SearchProduct.Item.Enrichment.BuyURL)
 				if err != nil {
 					return nil, err
 				}
-				zone = s.CachedLayout.Zone(strings.ToLower(department))
+				zone = s.Layout().Zone(strings.ToLower(department))
 				if zone == nil {
 					return nil, errors.New("locate product: could not find position " + name + " or department " + department)
 				}
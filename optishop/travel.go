@@ -0,0 +1,164 @@
+package optishop
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultAisleSpacing is the typical distance, in
+	// meters, between the centers of neighboring aisles.
+	DefaultAisleSpacing = 3.0
+
+	// DefaultStoreSize is the assumed size, in meters, of
+	// the largest floor of a store when there are not
+	// enough aisles to estimate its scale.
+	DefaultStoreSize = 100.0
+
+	// DefaultWalkingSpeed is the typical walking speed of
+	// a shopper, in meters per second.
+	DefaultWalkingSpeed = 1.0
+)
+
+// A ScaledStore is a Store which knows the physical scale
+// of its layout.
+type ScaledStore interface {
+	Store
+
+	// MetersPerUnit gets the number of meters in a single
+	// unit of layout coordinates.
+	MetersPerUnit() float64
+}
+
+// DefaultPortalTimes creates a reasonable PortalCost for
+// each PortalType, measured in seconds.
+func DefaultPortalTimes() map[PortalType]PortalCost {
+	return map[PortalType]PortalCost{
+		Elevator:  PortalCost{Fixed: 45, PerFloor: 10},
+		Escalator: PortalCost{PerFloor: 25},
+		Stairs:    PortalCost{PerFloor: 20},
+	}
+}
+
+// EstimateScale estimates the number of meters in a
+// single unit of layout coordinates, assuming that the
+// median distance between each aisle and its closest
+// neighboring aisle is aisleSpacing meters.
+//
+// If the layout does not have enough aisles, the largest
+// floor is assumed to be DefaultStoreSize meters across.
+func EstimateScale(layout *Layout, aisleSpacing float64) float64 {
+	var spacings []float64
+	for _, floor := range layout.Floors {
+		for _, z := range floor.Zones {
+			if !z.Specific {
+				continue
+			}
+			closest := math.Inf(1)
+			for _, z1 := range floor.Zones {
+				if z1.Specific && z1 != z && z1.Location != z.Location {
+					closest = math.Min(closest, z.Location.Distance(z1.Location))
+				}
+			}
+			if !math.IsInf(closest, 1) {
+				spacings = append(spacings, closest)
+			}
+		}
+	}
+	if len(spacings) < 2 {
+		if size := LayoutSize(layout); size > 0 {
+			return DefaultStoreSize / size
+		}
+		return 1
+	}
+	sort.Float64s(spacings)
+	return aisleSpacing / spacings[len(spacings)/2]
+}
+
+// A TravelEstimate is the estimated distance and time it
+// takes to travel along a path.
+type TravelEstimate struct {
+	// Distance is the walking distance in meters.
+	Distance float64
+
+	// Time includes both walking and portal time.
+	Time time.Duration
+}
+
+// Add computes the total of two estimates.
+func (t TravelEstimate) Add(t1 TravelEstimate) TravelEstimate {
+	return TravelEstimate{Distance: t.Distance + t1.Distance, Time: t.Time + t1.Time}
+}
+
+// A TravelEstimator estimates how long it takes a shopper
+// to travel through a layout.
+type TravelEstimator struct {
+	Layout *Layout
+
+	// MetersPerUnit is the physical scale of the layout.
+	MetersPerUnit float64
+
+	// WalkingSpeed is measured in meters per second.
+	// If 0, DefaultWalkingSpeed is used.
+	WalkingSpeed float64
+
+	// PortalTimes is measured in seconds.
+	// If nil, DefaultPortalTimes() is used.
+	PortalTimes map[PortalType]PortalCost
+}
+
+// NewTravelEstimator creates a TravelEstimator for a
+// store, using the store's scale if it is a ScaledStore,
+// or estimating the scale from its aisles otherwise.
+func NewTravelEstimator(store Store) *TravelEstimator {
+	layout := store.Layout()
+	var scale float64
+	if s, ok := store.(ScaledStore); ok {
+		scale = s.MetersPerUnit()
+	} else {
+		scale = EstimateScale(layout, DefaultAisleSpacing)
+	}
+	return &TravelEstimator{Layout: layout, MetersPerUnit: scale}
+}
+
+// Estimate estimates the distance and time it takes to
+// travel along the path.
+func (t *TravelEstimator) Estimate(path FloorPath) TravelEstimate {
+	speed := t.WalkingSpeed
+	if speed == 0 {
+		speed = DefaultWalkingSpeed
+	}
+	portalTimes := t.PortalTimes
+	if portalTimes == nil {
+		portalTimes = DefaultPortalTimes()
+	}
+
+	var distance, seconds float64
+	for i, step := range path {
+		distance += step.Path.Length() * t.MetersPerUnit
+		if i+1 < len(path) {
+			numFloors := path[i+1].Floor - step.Floor
+			seconds += t.portalTime(portalTimes, t.Layout.Portal(step.SourcePortal), numFloors)
+		}
+	}
+	seconds += distance / speed
+	return TravelEstimate{
+		Distance: distance,
+		Time:     time.Duration(seconds * float64(time.Second)),
+	}
+}
+
+func (t *TravelEstimator) portalTime(times map[PortalType]PortalCost, portal *Portal,
+	numFloors int) float64 {
+	if portal != nil {
+		if cost, ok := times[portal.Type]; ok {
+			return cost.Cost(numFloors)
+		}
+	}
+	var maxTime float64
+	for _, cost := range times {
+		maxTime = math.Max(maxTime, cost.Cost(numFloors))
+	}
+	return maxTime
+}
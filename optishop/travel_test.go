@@ -0,0 +1,55 @@
+package optishop
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEstimateScale(t *testing.T) {
+	layout := portalChoiceLayout()
+	for i := 0; i < 5; i++ {
+		layout.Floors[0].Zones = append(layout.Floors[0].Zones, &Zone{
+			Location: Point{X: 1 + float64(i)*2, Y: 5},
+			Specific: true,
+		})
+	}
+	if scale := EstimateScale(layout, 3); math.Abs(scale-1.5) > 1e-8 {
+		t.Errorf("expected scale 1.5 but got %f", scale)
+	}
+
+	layout = portalChoiceLayout()
+	if scale := EstimateScale(layout, 3); math.Abs(scale-DefaultStoreSize/10) > 1e-8 {
+		t.Errorf("unexpected fallback scale %f", scale)
+	}
+}
+
+func TestTravelEstimator(t *testing.T) {
+	layout := portalChoiceLayout()
+	estimator := &TravelEstimator{
+		Layout:        layout,
+		MetersPerUnit: 2,
+		WalkingSpeed:  4,
+	}
+	path := FloorPath{
+		&FloorPathStep{
+			Floor:        0,
+			Path:         Path{{8, 0}, {8, 8}},
+			SourcePortal: 2,
+			DestPortal:   3,
+		},
+		&FloorPathStep{
+			Floor: 1,
+			Path:  Path{{8, 8}, {5, 8}},
+		},
+	}
+	estimate := estimator.Estimate(path)
+	if math.Abs(estimate.Distance-22) > 1e-8 {
+		t.Errorf("expected distance 22 but got %f", estimate.Distance)
+	}
+	expectedTime := time.Duration(5.5*float64(time.Second)) +
+		time.Duration(DefaultPortalTimes()[Escalator].Cost(1)*float64(time.Second))
+	if math.Abs(float64(estimate.Time-expectedTime)) > float64(time.Millisecond) {
+		t.Errorf("expected time %v but got %v", expectedTime, estimate.Time)
+	}
+}
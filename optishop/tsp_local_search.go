@@ -0,0 +1,184 @@
+package optishop
+
+import (
+	"context"
+
+	"github.com/unixpickle/essentials"
+)
+
+// localSearchEpsilon is the minimum improvement for a move
+// to be applied, preventing infinite loops due to
+// rounding errors.
+const localSearchEpsilon = 1e-8
+
+// LocalSearchTSPSolver is a TSPSolver that refines the
+// route from another TSPSolver using 2-opt and Or-opt
+// moves until no move can shorten the route.
+//
+// The first and last points of the route are never moved,
+// so the route always starts at point 0 and ends at point
+// n - 1.
+//
+// Distances need not be symmetric.
+type LocalSearchTSPSolver struct {
+	// Initial computes the route to refine.
+	// If nil, GreedyTSPSolver is used.
+	Initial TSPSolver
+
+	// MaxSegment is the longest sequence of stops that an
+	// Or-opt move may relocate.
+	// If 0, a default of 3 is used.
+	MaxSegment int
+}
+
+// SolveTSP computes an initial route and then refines it.
+func (l LocalSearchTSPSolver) SolveTSP(n int, distance func(a, b int) float64) []int {
+	return l.SolveTSPContext(context.Background(), n, distance)
+}
+
+// SolveTSPContext is like SolveTSP, except that it stops
+// refining the route once ctx is done.
+//
+// If Initial is a ContextTSPSolver, it is passed ctx as
+// well.
+func (l LocalSearchTSPSolver) SolveTSPContext(ctx context.Context, n int,
+	distance func(a, b int) float64) []int {
+	var initial TSPSolver = GreedyTSPSolver{}
+	if l.Initial != nil {
+		initial = l.Initial
+	}
+	route := solveTSPContext(ctx, initial, n, distance)
+	return l.ImproveContext(ctx, route, distance)
+}
+
+// Improve refines an existing route, returning a new route
+// which is no longer than the original.
+func (l LocalSearchTSPSolver) Improve(route []int, distance func(a, b int) float64) []int {
+	return l.ImproveContext(context.Background(), route, distance)
+}
+
+// ImproveContext is like Improve, except that it returns
+// the best route so far once ctx is done.
+func (l LocalSearchTSPSolver) ImproveContext(ctx context.Context, route []int,
+	distance func(a, b int) float64) []int {
+	route = append([]int{}, route...)
+	maxSegment := l.MaxSegment
+	if maxSegment == 0 {
+		maxSegment = 3
+	}
+	for !contextDone(ctx) {
+		costs := newRouteCosts(route, distance)
+		if r := twoOptMove(route, distance, costs); r != nil {
+			route = r
+			continue
+		}
+		if r := orOptMove(route, distance, costs, maxSegment); r != nil {
+			route = r
+			continue
+		}
+		break
+	}
+	return route
+}
+
+// routeCosts stores cumulative sums of edge costs along a
+// route, both forwards and backwards, so that the cost of
+// reversing any segment can be found in O(1) time.
+type routeCosts struct {
+	forward  []float64
+	backward []float64
+}
+
+func newRouteCosts(route []int, distance func(a, b int) float64) *routeCosts {
+	res := &routeCosts{
+		forward:  make([]float64, len(route)),
+		backward: make([]float64, len(route)),
+	}
+	for i := 1; i < len(route); i++ {
+		res.forward[i] = res.forward[i-1] + distance(route[i-1], route[i])
+		res.backward[i] = res.backward[i-1] + distance(route[i], route[i-1])
+	}
+	return res
+}
+
+// Forward gets the cost of the segment route[i:j+1].
+func (r *routeCosts) Forward(i, j int) float64 {
+	return r.forward[j] - r.forward[i]
+}
+
+// Backward gets the cost of the segment route[i:j+1] when
+// traversed in reverse.
+func (r *routeCosts) Backward(i, j int) float64 {
+	return r.backward[j] - r.backward[i]
+}
+
+// twoOptMove finds a segment which can be reversed to
+// shorten the route, returning the new route or nil if no
+// improvement was found.
+func twoOptMove(route []int, distance func(a, b int) float64, costs *routeCosts) []int {
+	n := len(route)
+	for i := 1; i < n-2; i++ {
+		for j := i + 1; j < n-1; j++ {
+			oldCost := distance(route[i-1], route[i]) + costs.Forward(i, j) +
+				distance(route[j], route[j+1])
+			newCost := distance(route[i-1], route[j]) + costs.Backward(i, j) +
+				distance(route[i], route[j+1])
+			if newCost < oldCost-localSearchEpsilon {
+				res := append([]int{}, route...)
+				essentials.Reverse(res[i : j+1])
+				return res
+			}
+		}
+	}
+	return nil
+}
+
+// orOptMove finds a short segment which can be moved
+// (and possibly reversed) to shorten the route, returning
+// the new route or nil if no improvement was found.
+func orOptMove(route []int, distance func(a, b int) float64, costs *routeCosts,
+	maxSegment int) []int {
+	n := len(route)
+	for length := 1; length <= maxSegment; length++ {
+		for i := 1; i+length <= n-1; i++ {
+			end := i + length - 1
+			removeGain := distance(route[i-1], route[i]) + distance(route[end], route[end+1]) -
+				distance(route[i-1], route[end+1])
+			for p := 0; p < n-1; p++ {
+				if p >= i-1 && p <= end {
+					continue
+				}
+				a, b := route[p], route[p+1]
+				forwardCost := distance(a, route[i]) + distance(route[end], b) - distance(a, b)
+				backwardCost := distance(a, route[end]) + distance(route[i], b) - distance(a, b) +
+					costs.Backward(i, end) - costs.Forward(i, end)
+				if forwardCost < removeGain-localSearchEpsilon {
+					return moveSegment(route, i, end, p, false)
+				} else if backwardCost < removeGain-localSearchEpsilon {
+					return moveSegment(route, i, end, p, true)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// moveSegment moves route[start:end+1] to come directly
+// after route[dest], optionally reversing it.
+func moveSegment(route []int, start, end, dest int, reverse bool) []int {
+	segment := append([]int{}, route[start:end+1]...)
+	if reverse {
+		essentials.Reverse(segment)
+	}
+	res := make([]int, 0, len(route))
+	for i, x := range route {
+		if i >= start && i <= end {
+			continue
+		}
+		res = append(res, x)
+		if i == dest {
+			res = append(res, segment...)
+		}
+	}
+	return res
+}
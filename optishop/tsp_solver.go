@@ -1,6 +1,7 @@
 package optishop
 
 import (
+	"context"
 	"math"
 	"sort"
 )
@@ -18,6 +19,20 @@ type TSPSolver interface {
 	SolveTSP(n int, distance func(a, b int) float64) []int
 }
 
+// A ContextTSPSolver is a TSPSolver that can be stopped
+// early by a context.
+type ContextTSPSolver interface {
+	TSPSolver
+
+	// SolveTSPContext is like SolveTSP, except that it
+	// returns early if ctx is done.
+	//
+	// When stopped early, the best route found so far is
+	// returned, which is always a valid (but perhaps
+	// inefficient) route.
+	SolveTSPContext(ctx context.Context, n int, distance func(a, b int) float64) []int
+}
+
 // SolveTSP solves a Traveling salesman problem quickly,
 // potentially using an approximation if an exact solution
 // is infeasible.
@@ -25,15 +40,130 @@ type TSPSolver interface {
 // This function is like TSPSolver.SolveTSP, except that
 // it automatically selects an appropriate TSPSolver.
 func SolveTSP(n int, distance func(a, b int) float64) []int {
-	if n <= 10 {
-		return (FactorialTSPSolver{}).SolveTSP(n, distance)
-	} else if n <= 30 {
-		return (BeamTSPSolver{BeamSize: 1000}).SolveTSP(n, distance)
+	return SolveTSPContext(context.Background(), n, distance)
+}
+
+// SolveTSPContext is like SolveTSP, except that it returns
+// the best route found so far once ctx is done.
+func SolveTSPContext(ctx context.Context, n int, distance func(a, b int) float64) []int {
+	// Held-Karp takes roughly 100ms for 18 points, and its
+	// run time grows by about 4x with each extra point.
+	if n <= 18 {
+		return (HeldKarpTSPSolver{}).SolveTSPContext(ctx, n, distance)
+	}
+	var initial TSPSolver
+	if n <= 30 {
+		initial = BeamTSPSolver{BeamSize: 1000}
 	} else if n <= 50 {
-		return (BeamTSPSolver{BeamSize: 100}).SolveTSP(n, distance)
+		initial = BeamTSPSolver{BeamSize: 100}
 	} else {
-		return (GreedyTSPSolver{}).SolveTSP(n, distance)
+		initial = GreedyTSPSolver{}
+	}
+	return (LocalSearchTSPSolver{Initial: initial}).SolveTSPContext(ctx, n, distance)
+}
+
+// solveTSPContext runs a TSPSolver with a context if the
+// solver supports it.
+func solveTSPContext(ctx context.Context, s TSPSolver, n int,
+	distance func(a, b int) float64) []int {
+	if cs, ok := s.(ContextTSPSolver); ok {
+		return cs.SolveTSPContext(ctx, n, distance)
+	}
+	return s.SolveTSP(n, distance)
+}
+
+// contextDone checks if a context is done without
+// blocking.
+func contextDone(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// GroupedTSPSolver is a TSPSolver which visits points in
+// order of precedence groups, e.g. so that some points are
+// only visited after all of the others.
+//
+// Points in lower groups are visited before points in
+// higher groups. The first and last points are always
+// visited first and last, regardless of their groups.
+type GroupedTSPSolver struct {
+	// Groups specifies the group of each point.
+	Groups []int
+
+	// Solver is used to solve the underlying problem.
+	// If nil, a solver is selected automatically, like in
+	// SolveTSP.
+	Solver TSPSolver
+}
+
+// SolveTSP generates a solution which respects the
+// groups.
+func (g GroupedTSPSolver) SolveTSP(n int, distance func(a, b int) float64) []int {
+	return g.SolveTSPContext(context.Background(), n, distance)
+}
+
+// SolveTSPContext generates a solution which respects the
+// groups, stopping early if ctx is done.
+func (g GroupedTSPSolver) SolveTSPContext(ctx context.Context, n int,
+	distance func(a, b int) float64) []int {
+	penalized := g.penalizedDistance(n, distance)
+
+	var route []int
+	if g.Solver == nil {
+		route = SolveTSPContext(ctx, n, penalized)
+	} else {
+		route = solveTSPContext(ctx, g.Solver, n, penalized)
+	}
+	if g.valid(route) {
+		return route
+	}
+
+	// Approximate solvers may visit groups out of order,
+	// in which case we fix the order and then attempt to
+	// make up for lost efficiency.
+	route = append([]int{}, route...)
+	middle := route[1 : len(route)-1]
+	sort.SliceStable(middle, func(i, j int) bool {
+		return g.Groups[middle[i]] < g.Groups[middle[j]]
+	})
+	return (LocalSearchTSPSolver{}).ImproveContext(ctx, route, penalized)
+}
+
+// penalizedDistance creates a distance function which
+// makes it more expensive to go from a higher group to a
+// lower group than to take any valid route.
+//
+// As a result, exact solvers always respect the groups,
+// and local search never breaks them.
+func (g GroupedTSPSolver) penalizedDistance(n int,
+	distance func(a, b int) float64) func(a, b int) float64 {
+	penalty := 1.0
+	for a := 0; a < n; a++ {
+		var maxDist float64
+		for b := 0; b < n; b++ {
+			maxDist = math.Max(maxDist, distance(a, b))
+		}
+		penalty += maxDist
+	}
+	return func(a, b int) float64 {
+		if a != 0 && b != n-1 && g.Groups[a] > g.Groups[b] {
+			return distance(a, b) + penalty
+		}
+		return distance(a, b)
+	}
+}
+
+func (g GroupedTSPSolver) valid(route []int) bool {
+	for i := 2; i < len(route)-1; i++ {
+		if g.Groups[route[i-1]] > g.Groups[route[i]] {
+			return false
+		}
 	}
+	return true
 }
 
 // GreedyTSPSolver is a TSPSolver that uses the nearest
@@ -42,9 +172,18 @@ type GreedyTSPSolver struct{}
 
 // SolveTSP generates a greedy solution to the TSP.
 func (g GreedyTSPSolver) SolveTSP(n int, distance func(a, b int) float64) []int {
-	visited := map[int]bool{0: true}
-	route := []int{0}
-	p := 0
+	return greedyCompleteRoute(n, distance, []int{0})
+}
+
+// greedyCompleteRoute extends a partial route using the
+// nearest neighbor algorithm.
+func greedyCompleteRoute(n int, distance func(a, b int) float64, prefix []int) []int {
+	visited := map[int]bool{}
+	for _, x := range prefix {
+		visited[x] = true
+	}
+	route := append([]int{}, prefix...)
+	p := route[len(route)-1]
 	for len(route) < n-1 {
 		minDist := math.Inf(1)
 		minNode := 0
@@ -106,6 +245,90 @@ func (f FactorialTSPSolver) recurse(length int, distance float64, used []bool, p
 	}
 }
 
+// HeldKarpTSPSolver is a TSPSolver that uses the
+// Held-Karp dynamic programming algorithm to find exact
+// solutions in O(n^2 * 2^n) time and O(n * 2^n) memory.
+type HeldKarpTSPSolver struct{}
+
+// SolveTSP generates an exact solution to the TSP.
+func (h HeldKarpTSPSolver) SolveTSP(n int, distance func(a, b int) float64) []int {
+	return h.SolveTSPContext(context.Background(), n, distance)
+}
+
+// SolveTSPContext generates an exact solution to the TSP,
+// or a greedy solution if ctx is done first.
+func (h HeldKarpTSPSolver) SolveTSPContext(ctx context.Context, n int,
+	distance func(a, b int) float64) []int {
+	if n <= 2 {
+		solution := make([]int, n)
+		for i := range solution {
+			solution[i] = i
+		}
+		return solution
+	}
+
+	distances := make([][]float64, n)
+	for i := range distances {
+		distances[i] = make([]float64, n)
+		for j := range distances[i] {
+			distances[i][j] = distance(i, j)
+		}
+	}
+
+	// Stops are the points other than the start and end.
+	// Stop i corresponds to point i+1, and costs[mask*m+i]
+	// is the cost of the shortest path which starts at
+	// point 0, visits every stop in mask, and ends at stop
+	// i.
+	m := n - 2
+	costs := make([]float64, (1<<uint(m))*m)
+	for mask := 1; mask < 1<<uint(m); mask++ {
+		if mask%1024 == 0 && contextDone(ctx) {
+			return GreedyTSPSolver{}.SolveTSP(n, distance)
+		}
+		for i := 0; i < m; i++ {
+			if mask&(1<<uint(i)) == 0 {
+				continue
+			}
+			prevMask := mask ^ (1 << uint(i))
+			if prevMask == 0 {
+				costs[mask*m+i] = distances[0][i+1]
+				continue
+			}
+			best := math.Inf(1)
+			for j := 0; j < m; j++ {
+				if prevMask&(1<<uint(j)) != 0 {
+					best = math.Min(best, costs[prevMask*m+j]+distances[j+1][i+1])
+				}
+			}
+			costs[mask*m+i] = best
+		}
+	}
+
+	mask := 1<<uint(m) - 1
+	solution := make([]int, n)
+	solution[n-1] = n - 1
+	next := n - 1
+	for idx := n - 2; idx > 0; idx-- {
+		bestStop := -1
+		bestCost := math.Inf(1)
+		for i := 0; i < m; i++ {
+			if mask&(1<<uint(i)) != 0 {
+				cost := costs[mask*m+i] + distances[i+1][next]
+				if bestStop == -1 || cost < bestCost {
+					bestStop = i
+					bestCost = cost
+				}
+			}
+		}
+		solution[idx] = bestStop + 1
+		next = bestStop + 1
+		mask ^= 1 << uint(bestStop)
+	}
+
+	return solution
+}
+
 // BeamTSPSolver is a TSPSolver that uses beam search to
 // find good solutions which are optimal if the search
 // problem is small, but always fast regardless of the
@@ -119,9 +342,26 @@ type BeamTSPSolver struct {
 // SolveTSP generates an approximate (or sometimes excact)
 // solution to the TSP in no more than O(n^3 * BeamSize).
 func (b BeamTSPSolver) SolveTSP(n int, distance func(a, b int) float64) []int {
+	return b.SolveTSPContext(context.Background(), n, distance)
+}
+
+// SolveTSPContext is like SolveTSP, except that the best
+// partial solution is completed greedily if ctx is done
+// before the search finishes.
+func (b BeamTSPSolver) SolveTSPContext(ctx context.Context, n int,
+	distance func(a, b int) float64) []int {
 	nodes := []beamSearchNode{beamSearchNode{solution: []int{0}}}
 
 	for i := 0; i < n-2; i++ {
+		if contextDone(ctx) {
+			best := nodes[0]
+			for _, node := range nodes {
+				if node.distance < best.distance {
+					best = node
+				}
+			}
+			return greedyCompleteRoute(n, distance, best.solution)
+		}
 		newNodes := make([]beamSearchNode, 0, len(nodes)*(n-2-i))
 		for _, node := range nodes {
 			for j := 1; j < n-1; j++ {
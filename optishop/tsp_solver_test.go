@@ -1,8 +1,14 @@
 package optishop
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"math/rand"
 	"testing"
+	"time"
+
+	"github.com/unixpickle/approb"
 )
 
 func TestFactorialTSPSolver(t *testing.T) {
@@ -36,6 +42,167 @@ func TestBeamTSPSolver(t *testing.T) {
 	}
 }
 
+func TestHeldKarpTSPSolver(t *testing.T) {
+	distances := testingTSPProblem()
+	solution := (HeldKarpTSPSolver{}).SolveTSP(10, distances)
+	expected := []int{0, 8, 3, 1, 2, 7, 6, 5, 4, 9}
+	if len(solution) != len(expected) {
+		t.Fatal("incorrect length")
+	}
+	for i, x := range expected {
+		if x != solution[i] {
+			t.Errorf("expected solution %v but got %v", expected, solution)
+			break
+		}
+	}
+
+	rng := rand.New(rand.NewSource(1337))
+	for trial := 0; trial < 50; trial++ {
+		n := 2 + rng.Intn(8)
+		distances := randomTSPProblem(rng, n, trial%2 == 0)
+		exact := (FactorialTSPSolver{}).SolveTSP(n, distances)
+		actual := (HeldKarpTSPSolver{}).SolveTSP(n, distances)
+		checkTSPSolution(t, n, actual)
+		expectedLen := tspRouteLength(exact, distances)
+		actualLen := tspRouteLength(actual, distances)
+		if math.Abs(expectedLen-actualLen) > 1e-8 {
+			t.Fatalf("trial %d: expected length %f but got %f", trial, expectedLen, actualLen)
+		}
+	}
+}
+
+func TestSolveTSPContext(t *testing.T) {
+	distances := testingTSPProblem()
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		for _, n := range []int{2, 10, 25, 50} {
+			checkTSPSolution(t, n, SolveTSPContext(ctx, n, distances))
+		}
+		solvers := []ContextTSPSolver{
+			HeldKarpTSPSolver{},
+			BeamTSPSolver{BeamSize: 100},
+			LocalSearchTSPSolver{Initial: BeamTSPSolver{BeamSize: 100}},
+		}
+		for _, solver := range solvers {
+			checkTSPSolution(t, 15, solver.SolveTSPContext(ctx, 15, distances))
+		}
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancel()
+		start := time.Now()
+		solution := (BeamTSPSolver{BeamSize: 100000}).SolveTSPContext(ctx, 50, distances)
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("solver took too long: %v", elapsed)
+		}
+		checkTSPSolution(t, 50, solution)
+	})
+
+	t.Run("Uncancelled", func(t *testing.T) {
+		expected := (HeldKarpTSPSolver{}).SolveTSP(10, distances)
+		actual := SolveTSPContext(context.Background(), 10, distances)
+		if math.Abs(tspRouteLength(expected, distances)-tspRouteLength(actual, distances)) > 1e-8 {
+			t.Error("unexpected solution without cancellation")
+		}
+	})
+}
+
+func TestGroupedTSPSolver(t *testing.T) {
+	rng := rand.New(rand.NewSource(1337))
+	for trial := 0; trial < 30; trial++ {
+		n := 3 + rng.Intn(6)
+		distances := randomTSPProblem(rng, n, trial%2 == 0)
+		groups := make([]int, n)
+		for i := range groups {
+			groups[i] = rng.Intn(3)
+		}
+
+		// Brute force the best route that respects the
+		// groups.
+		bestLen := math.Inf(1)
+		for perm := range approb.Perms(n - 2) {
+			route := []int{0}
+			for _, x := range perm {
+				route = append(route, x+1)
+			}
+			route = append(route, n-1)
+			if !checkTSPGroups(route, groups) {
+				continue
+			}
+			bestLen = math.Min(bestLen, tspRouteLength(route, distances))
+		}
+
+		solvers := []TSPSolver{nil, HeldKarpTSPSolver{}, GreedyTSPSolver{},
+			BeamTSPSolver{BeamSize: 2}}
+		for i, solver := range solvers {
+			route := (GroupedTSPSolver{Groups: groups, Solver: solver}).SolveTSP(n, distances)
+			checkTSPSolution(t, n, route)
+			if !checkTSPGroups(route, groups) {
+				t.Fatalf("trial %d solver %d: route %v breaks groups %v", trial, i, route, groups)
+			}
+			length := tspRouteLength(route, distances)
+			if length < bestLen-1e-8 {
+				t.Fatalf("trial %d solver %d: route better than optimal", trial, i)
+			} else if i < 2 && length > bestLen+1e-8 {
+				t.Fatalf("trial %d solver %d: expected length %f but got %f", trial, i,
+					bestLen, length)
+			}
+		}
+	}
+}
+
+func TestLocalSearchTSPSolver(t *testing.T) {
+	rng := rand.New(rand.NewSource(1337))
+	var exactTotal, greedyTotal, localTotal float64
+	for trial := 0; trial < 50; trial++ {
+		n := 3 + rng.Intn(7)
+		distances := randomTSPProblem(rng, n, trial%2 == 0)
+
+		exact := (FactorialTSPSolver{}).SolveTSP(n, distances)
+		greedy := (GreedyTSPSolver{}).SolveTSP(n, distances)
+		local := (LocalSearchTSPSolver{}).SolveTSP(n, distances)
+		checkTSPSolution(t, n, local)
+
+		exactLen := tspRouteLength(exact, distances)
+		greedyLen := tspRouteLength(greedy, distances)
+		localLen := tspRouteLength(local, distances)
+		if localLen < exactLen-1e-8 {
+			t.Fatalf("trial %d: local search beat exact solution", trial)
+		} else if localLen > greedyLen+1e-8 {
+			t.Fatalf("trial %d: local search was worse than its initial route", trial)
+		}
+
+		improved := (LocalSearchTSPSolver{}).Improve(exact, distances)
+		if math.Abs(tspRouteLength(improved, distances)-exactLen) > 1e-8 {
+			t.Fatalf("trial %d: improving exact solution changed its length", trial)
+		}
+
+		exactTotal += exactLen
+		greedyTotal += greedyLen
+		localTotal += localLen
+	}
+	if localTotal > exactTotal*1.02 {
+		t.Errorf("local search too far from optimal: %f vs %f", localTotal, exactTotal)
+	}
+	if localTotal >= greedyTotal {
+		t.Errorf("local search did not improve on greedy: %f vs %f", localTotal, greedyTotal)
+	}
+}
+
+func TestLocalSearchTSPSolverLarge(t *testing.T) {
+	distances := testingTSPProblem()
+	beam := (BeamTSPSolver{BeamSize: 100}).SolveTSP(50, distances)
+	local := (LocalSearchTSPSolver{Initial: BeamTSPSolver{BeamSize: 100}}).SolveTSP(50,
+		distances)
+	checkTSPSolution(t, 50, local)
+	if tspRouteLength(local, distances) > tspRouteLength(beam, distances) {
+		t.Error("local search made beam search solution worse")
+	}
+}
+
 func BenchmarkFactorialTSPSolver(b *testing.B) {
 	distances := testingTSPProblem()
 	b.ResetTimer()
@@ -44,6 +211,17 @@ func BenchmarkFactorialTSPSolver(b *testing.B) {
 	}
 }
 
+func BenchmarkHeldKarpTSPSolver(b *testing.B) {
+	distances := testingTSPProblem()
+	for _, n := range []int{10, 13, 16, 18, 20} {
+		b.Run(fmt.Sprintf("Entries%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				(HeldKarpTSPSolver{}).SolveTSP(n, distances)
+			}
+		})
+	}
+}
+
 func BenchmarkBeamTSPSolver(b *testing.B) {
 	distances := testingTSPProblem()
 	b.Run("Beam100K", func(b *testing.B) {
@@ -68,6 +246,20 @@ func BenchmarkBeamTSPSolver(b *testing.B) {
 	})
 }
 
+func BenchmarkLocalSearchTSPSolver(b *testing.B) {
+	distances := testingTSPProblem()
+	b.Run("Greedy50", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			(LocalSearchTSPSolver{}).SolveTSP(50, distances)
+		}
+	})
+	b.Run("Beam100Entries50", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			(LocalSearchTSPSolver{Initial: BeamTSPSolver{BeamSize: 100}}).SolveTSP(50, distances)
+		}
+	})
+}
+
 func testingTSPProblem() func(i, j int) float64 {
 	// Points were randomly generated uniformly in [0, 1].
 	points := []Point{
@@ -133,3 +325,58 @@ func testingTSPProblem() func(i, j int) float64 {
 		return distances[i][j]
 	}
 }
+
+// randomTSPProblem creates a distance function for random
+// points, optionally adding noise to make it asymmetric.
+func randomTSPProblem(rng *rand.Rand, n int, asymmetric bool) func(i, j int) float64 {
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = Point{X: rng.Float64(), Y: rng.Float64()}
+	}
+	distances := make([][]float64, n)
+	for i, p := range points {
+		distances[i] = make([]float64, n)
+		for j, p1 := range points {
+			distances[i][j] = p.Distance(p1)
+			if asymmetric && i != j {
+				distances[i][j] += rng.Float64() * 0.2
+			}
+		}
+	}
+	return func(i, j int) float64 {
+		return distances[i][j]
+	}
+}
+
+func checkTSPSolution(t *testing.T, n int, solution []int) {
+	if len(solution) != n {
+		t.Fatalf("expected %d stops but got %d", n, len(solution))
+	}
+	if solution[0] != 0 || solution[n-1] != n-1 {
+		t.Fatalf("unexpected endpoints: %v", solution)
+	}
+	seen := map[int]bool{}
+	for _, x := range solution {
+		if seen[x] || x < 0 || x >= n {
+			t.Fatalf("invalid solution: %v", solution)
+		}
+		seen[x] = true
+	}
+}
+
+func tspRouteLength(route []int, distance func(i, j int) float64) float64 {
+	var res float64
+	for i := 1; i < len(route); i++ {
+		res += distance(route[i-1], route[i])
+	}
+	return res
+}
+
+func checkTSPGroups(route []int, groups []int) bool {
+	for i := 2; i < len(route)-1; i++ {
+		if groups[route[i-1]] > groups[route[i]] {
+			return false
+		}
+	}
+	return true
+}
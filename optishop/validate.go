@@ -0,0 +1,322 @@
+package optishop
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// A LayoutProblem is an issue with a Layout that may
+// cause routing to fail or behave unexpectedly.
+type LayoutProblem struct {
+	// Floor is the index of the floor with the problem, or
+	// -1 if the problem is not specific to a floor.
+	Floor int
+
+	// Location is the point where the problem occurs, or
+	// nil if the problem has no specific location.
+	Location *Point
+
+	Message string
+}
+
+// String formats the problem along with its floor and
+// location, if available.
+func (l *LayoutProblem) String() string {
+	var prefix string
+	if l.Floor >= 0 {
+		prefix = "floor " + strconv.Itoa(l.Floor)
+	}
+	if l.Location != nil {
+		if prefix != "" {
+			prefix += " "
+		}
+		prefix += fmt.Sprintf("(%.2f, %.2f)", l.Location.X, l.Location.Y)
+	}
+	if prefix == "" {
+		return l.Message
+	}
+	return prefix + ": " + l.Message
+}
+
+// ValidateLayout finds problems in a layout, such as zones
+// inside of obstacles, portals which lead to missing
+// portals, and zones that cannot be reached from the
+// entrance.
+//
+// Reachability is determined using a Raster for each
+// floor, so that the results match NewFloorConnector.
+//
+// Returns nil if no problems were found.
+func ValidateLayout(layout *Layout) []*LayoutProblem {
+	v := &layoutValidator{layout: layout}
+	if len(layout.Floors) == 0 {
+		v.add(-1, nil, "layout has no floors")
+		return v.problems
+	}
+	v.checkFloors()
+	v.checkZones()
+	v.checkPortals()
+	if v.boundsValid() {
+		v.checkReachability()
+	}
+	return v.problems
+}
+
+type layoutValidator struct {
+	layout   *Layout
+	problems []*LayoutProblem
+}
+
+func (v *layoutValidator) add(floor int, p *Point, msg string) {
+	var location *Point
+	if p != nil {
+		pCopy := *p
+		location = &pCopy
+	}
+	v.problems = append(v.problems, &LayoutProblem{
+		Floor:    floor,
+		Location: location,
+		Message:  msg,
+	})
+}
+
+func (v *layoutValidator) boundsValid() bool {
+	for _, floor := range v.layout.Floors {
+		_, _, w, h := floor.Bounds.Bounds()
+		if len(floor.Bounds) < 3 || w <= 0 || h <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *layoutValidator) checkFloors() {
+	for i, floor := range v.layout.Floors {
+		_, _, w, h := floor.Bounds.Bounds()
+		if len(floor.Bounds) < 3 || w <= 0 || h <= 0 {
+			v.add(i, nil, "floor bounds are empty")
+		}
+	}
+}
+
+func (v *layoutValidator) checkZones() {
+	var hasEntrance, hasCheckout bool
+	names := map[string]bool{}
+	for i, floor := range v.layout.Floors {
+		for _, zone := range floor.Zones {
+			hasEntrance = hasEntrance || zone.Entrance
+			hasCheckout = hasCheckout || zone.Checkout
+			desc := problemZoneName(zone)
+			if zone.Name != "" {
+				if names[zone.Name] {
+					v.add(i, &zone.Location, desc+" has a duplicate name")
+				}
+				names[zone.Name] = true
+			}
+			if !floor.Contains(zone.Location) {
+				v.add(i, &zone.Location, desc+" is outside of the floor")
+			} else if floor.InObstacle(zone.Location) {
+				v.add(i, &zone.Location, desc+" is inside of an obstacle")
+			}
+		}
+	}
+	if !hasEntrance {
+		v.add(-1, nil, "layout has no entrance")
+	}
+	if !hasCheckout {
+		v.add(-1, nil, "layout has no checkout")
+	}
+}
+
+func (v *layoutValidator) checkPortals() {
+	ids := map[int]bool{}
+	for i, floor := range v.layout.Floors {
+		for _, portal := range floor.Portals {
+			desc := problemPortalName(portal)
+			if ids[portal.ID] {
+				v.add(i, &portal.Location, desc+" has a duplicate ID")
+			}
+			ids[portal.ID] = true
+			if !floor.Contains(portal.Location) {
+				v.add(i, &portal.Location, desc+" is outside of the floor")
+			} else if floor.InObstacle(portal.Location) {
+				v.add(i, &portal.Location, desc+" is inside of an obstacle")
+			}
+			if len(portal.Destinations) == 0 {
+				v.add(i, &portal.Location, desc+" has no destinations")
+			}
+			for _, dest := range portal.Destinations {
+				destPortal := v.layout.Portal(dest)
+				if destPortal == nil {
+					v.add(i, &portal.Location,
+						desc+" leads to missing portal "+strconv.Itoa(dest))
+				} else if v.layout.PortalFloor(destPortal) == i {
+					v.add(i, &portal.Location,
+						desc+" leads to portal "+strconv.Itoa(dest)+" on the same floor")
+				}
+			}
+		}
+	}
+}
+
+// checkReachability makes sure that every zone and portal
+// can be reached from the entrances, and that an entrance
+// can be reached from every zone.
+//
+// If there are no entrances, checkouts are used instead.
+func (v *layoutValidator) checkReachability() {
+	rasters := make([]*Raster, len(v.layout.Floors))
+	labels := make([][]int, len(v.layout.Floors))
+	for i, floor := range v.layout.Floors {
+		rasters[i] = NewRaster(floor)
+		labels[i] = rasters[i].components()
+	}
+	region := func(floor int, p Point) layoutRegion {
+		return layoutRegion{Floor: floor, Component: rasters[floor].component(labels[floor], p)}
+	}
+
+	// Build a graph of regions which are connected by
+	// portals.
+	forward := map[layoutRegion][]layoutRegion{}
+	backward := map[layoutRegion][]layoutRegion{}
+	for i, floor := range v.layout.Floors {
+		for _, portal := range floor.Portals {
+			source := region(i, portal.Location)
+			for _, dest := range portal.Destinations {
+				destPortal := v.layout.Portal(dest)
+				if destPortal == nil {
+					continue
+				}
+				destFloor := v.layout.PortalFloor(destPortal)
+				target := region(destFloor, destPortal.Location)
+				forward[source] = append(forward[source], target)
+				backward[target] = append(backward[target], source)
+			}
+		}
+	}
+
+	var starts []layoutRegion
+	for _, useCheckouts := range []bool{false, true} {
+		for i, floor := range v.layout.Floors {
+			for _, zone := range floor.Zones {
+				if (!useCheckouts && zone.Entrance) || (useCheckouts && zone.Checkout) {
+					starts = append(starts, region(i, zone.Location))
+				}
+			}
+		}
+		if len(starts) > 0 {
+			break
+		}
+	}
+	if len(starts) == 0 {
+		return
+	}
+	fromStart := reachableRegions(starts, forward)
+	toStart := reachableRegions(starts, backward)
+
+	for i, floor := range v.layout.Floors {
+		for _, zone := range floor.Zones {
+			r := region(i, zone.Location)
+			desc := problemZoneName(zone)
+			if !fromStart[r] {
+				v.add(i, &zone.Location, desc+" cannot be reached from the entrance")
+			} else if !toStart[r] {
+				v.add(i, &zone.Location, "the entrance cannot be reached from "+desc)
+			}
+		}
+		for _, portal := range floor.Portals {
+			if !fromStart[region(i, portal.Location)] {
+				v.add(i, &portal.Location,
+					problemPortalName(portal)+" cannot be reached from the entrance")
+			}
+		}
+	}
+}
+
+type layoutRegion struct {
+	Floor     int
+	Component int
+}
+
+func reachableRegions(starts []layoutRegion,
+	edges map[layoutRegion][]layoutRegion) map[layoutRegion]bool {
+	visited := map[layoutRegion]bool{}
+	queue := []layoutRegion{}
+	for _, s := range starts {
+		if !visited[s] {
+			visited[s] = true
+			queue = append(queue, s)
+		}
+	}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, r := range edges[next] {
+			if !visited[r] {
+				visited[r] = true
+				queue = append(queue, r)
+			}
+		}
+	}
+	return visited
+}
+
+// components labels the connected regions of the raster,
+// using the same moves that Connect does.
+//
+// Obstructed points are labeled -1.
+func (r *Raster) components() []int {
+	labels := make([]int, r.width*r.height)
+	for i := range labels {
+		labels[i] = -1
+	}
+	numLabels := 0
+	for y := 0; y < r.height; y++ {
+		for x := 0; x < r.width; x++ {
+			start := rasterPoint{X: x, Y: y}
+			idx := r.pointToIndex(start)
+			if r.obstructed[idx] || labels[idx] != -1 {
+				continue
+			}
+			labels[idx] = numLabels
+			queue := []rasterPoint{start}
+			for len(queue) > 0 {
+				next := queue[0]
+				queue = queue[1:]
+				r.nearbyPoints(next, func(p rasterPoint) {
+					pIdx := r.pointToIndex(p)
+					if labels[pIdx] == -1 {
+						labels[pIdx] = numLabels
+						queue = append(queue, p)
+					}
+				})
+			}
+			numLabels++
+		}
+	}
+	return labels
+}
+
+// component finds the label of the region where Connect
+// would start a path from p.
+func (r *Raster) component(labels []int, p Point) int {
+	rp := r.pointToRaster(r.Unobstruct(p))
+	rp.X = clampDim(rp.X, r.width)
+	rp.Y = clampDim(rp.Y, r.height)
+	return labels[r.pointToIndex(rp)]
+}
+
+func problemZoneName(z *Zone) string {
+	if z.Name != "" {
+		return "zone " + strconv.Quote(z.Name)
+	} else if z.Entrance {
+		return "entrance"
+	} else if z.Checkout {
+		return "checkout"
+	}
+	return "unnamed zone"
+}
+
+func problemPortalName(p *Portal) string {
+	return string(p.Type) + " portal " + strconv.Itoa(p.ID)
+}
@@ -0,0 +1,72 @@
+package optishop
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateLayoutValid(t *testing.T) {
+	layout := testValidationLayout()
+	if problems := ValidateLayout(layout); len(problems) != 0 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
+
+func TestValidateLayoutProblems(t *testing.T) {
+	layout := testValidationLayout()
+	floor := layout.Floors[0]
+
+	// Wall off the right side of the floor.
+	floor.Obstacles = append(floor.Obstacles, Polygon{
+		{X: 6, Y: -1}, {X: 7, Y: -1}, {X: 7, Y: 11}, {X: 6, Y: 11},
+	})
+	floor.Zones = append(floor.Zones,
+		&Zone{Name: "A2", Location: Point{X: 9, Y: 5}},
+		&Zone{Name: "A3", Location: Point{X: 3, Y: 3}},
+	)
+	floor.Portals[0].Destinations = []int{1, 5}
+
+	expected := []string{
+		`zone "A3" is inside of an obstacle`,
+		`escalator portal 0 leads to missing portal 5`,
+		`zone "A2" cannot be reached from the entrance`,
+	}
+	problems := ValidateLayout(layout)
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems but got %v", len(expected), problems)
+	}
+	for i, problem := range problems {
+		if !strings.HasSuffix(problem.String(), expected[i]) {
+			t.Errorf("problem %d: expected %q but got %q", i, expected[i], problem.String())
+		}
+	}
+}
+
+func testValidationLayout() *Layout {
+	bounds := Polygon{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}
+	return &Layout{
+		Floors: []*Floor{
+			{
+				Bounds:    bounds,
+				Obstacles: []Polygon{{{X: 2, Y: 2}, {X: 4, Y: 2}, {X: 4, Y: 4}, {X: 2, Y: 4}}},
+				Zones: []*Zone{
+					{Location: Point{X: 1, Y: 1}, Entrance: true},
+					{Location: Point{X: 1, Y: 9}, Checkout: true},
+					{Name: "A1", Location: Point{X: 5, Y: 5}, Specific: true},
+				},
+				Portals: []*Portal{
+					{Location: Point{X: 5, Y: 8}, Type: Escalator, ID: 0, Destinations: []int{1}},
+				},
+			},
+			{
+				Bounds: bounds,
+				Zones: []*Zone{
+					{Name: "B1", Location: Point{X: 5, Y: 5}, Specific: true},
+				},
+				Portals: []*Portal{
+					{Location: Point{X: 5, Y: 8}, Type: Escalator, ID: 1, Destinations: []int{0}},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package visualize
+
+import (
+	"fmt"
+	"strings"
+
+	svg "github.com/ajstarks/svgo/float"
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+const (
+	// AnimationSpeedFrac controls how fast routes are
+	// traced in animations, as a fraction of the size of
+	// the layout per second.
+	AnimationSpeedFrac = 0.2
+
+	// AnimationPause is the number of seconds that an
+	// animation waits at each stop and floor transition.
+	AnimationPause = 0.75
+)
+
+// DrawAnimatedRoute draws a route that is traced out leg
+// by leg using SMIL animations, pausing at the end of
+// every leg and at every floor transition.
+//
+// It returns the duration of the animation in seconds.
+func (t *Theme) DrawAnimatedRoute(canvas *svg.SVG, layout *optishop.Layout,
+	paths []optishop.FloorPath) float64 {
+	width, _, _ := MultiFloorGeometry(layout)
+	speed := width * AnimationSpeedFrac
+	pathWidth := t.PathWidth(layout)
+	pathColor := cssColor(t.Path)
+
+	var begin float64
+	for i, path := range paths {
+		canvas.Group(`class="route-path animated"`, fmt.Sprintf(`data-leg="%d"`, i),
+			`aria-hidden="true"`)
+		for j, step := range path {
+			if len(step.Path) < 2 {
+				begin += AnimationPause
+				continue
+			}
+			x, y := FloorOffset(layout, step.Floor)
+			var pathX, pathY []float64
+			var pathData []string
+			for k, p := range step.Path {
+				pathX = append(pathX, p.X+x)
+				pathY = append(pathY, p.Y+y)
+				cmd := "L"
+				if k == 0 {
+					cmd = "M"
+				}
+				pathData = append(pathData, fmt.Sprintf("%s%.3f,%.3f", cmd, p.X+x, p.Y+y))
+			}
+			duration := step.Path.Length() / speed
+			timing := []string{fmt.Sprintf(`begin="%.3fs"`, begin), `fill="freeze"`}
+
+			id := fmt.Sprintf("route-leg-%d-step-%d", i, j)
+			canvas.Polyline(pathX, pathY, fmt.Sprintf(`id="%s"`, id), `class="route-line"`,
+				`pathLength="100"`, fmt.Sprintf("stroke-width: %.3fpx; stroke: %s; fill: none; "+
+					"stroke-dasharray: 100; stroke-dashoffset: 100", pathWidth, pathColor))
+			canvas.Animate("#"+id, "stroke-dashoffset", 100, 0, duration, 1, timing...)
+
+			// A marker moves along with the end of the line.
+			canvas.Path(strings.Join(pathData, " "), fmt.Sprintf(`id="%s-motion"`, id),
+				"fill: none; stroke: none")
+			canvas.Circle(0, 0, pathWidth*2, fmt.Sprintf(`id="%s-marker"`, id),
+				`class="route-marker"`, `opacity="0"`, "fill: "+pathColor)
+			canvas.AnimateMotion("#"+id+"-marker", "#"+id+"-motion", duration, 1, timing...)
+			setAttribute(canvas, id+"-marker", "opacity", "1", begin)
+			setAttribute(canvas, id+"-marker", "opacity", "0", begin+duration)
+
+			begin += duration
+			if j+1 < len(path) {
+				begin += AnimationPause
+			}
+		}
+		canvas.Gend()
+		begin += AnimationPause
+	}
+	return begin
+}
+
+// DrawAnimatedRoute draws an animated route using the
+// DefaultTheme.
+func DrawAnimatedRoute(canvas *svg.SVG, layout *optishop.Layout,
+	paths []optishop.FloorPath) float64 {
+	return DefaultTheme.DrawAnimatedRoute(canvas, layout, paths)
+}
+
+// setAttribute emits a SMIL set element which changes an
+// attribute of an element at a given time.
+func setAttribute(canvas *svg.SVG, id, attr, value string, begin float64) {
+	fmt.Fprintf(canvas.Writer, `<set xlink:href="#%s" attributeName="%s" to="%s" `+
+		`begin="%.3fs" fill="freeze" />`+"\n", id, attr, value, begin)
+}
@@ -0,0 +1,117 @@
+package visualize
+
+import (
+	"fmt"
+	"html"
+	"math"
+
+	svg "github.com/ajstarks/svgo/float"
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+// HeatmapRadiusFrac controls how close an obstacle must be
+// to a zone, with respect to the size of the floor, for
+// the zone's frequency to count towards the obstacle.
+const HeatmapRadiusFrac = 0.05
+
+// A ZoneFrequency records how often a zone is visited.
+type ZoneFrequency struct {
+	Floor int
+	Zone  *optishop.Zone
+	Count int
+}
+
+// DrawHeatmap draws the floors of a layout which are
+// visible in a viewport, shading zones and the obstacles
+// around them by how frequently they are visited.
+//
+// Each obstacle is shaded by the total frequency of the
+// zones for which it is the closest obstacle.
+func (t *Theme) DrawHeatmap(canvas *svg.SVG, layout *optishop.Layout, v Viewport,
+	freqs []*ZoneFrequency) {
+	obstacleHeat := make([][]float64, len(layout.Floors))
+	for i, floor := range layout.Floors {
+		obstacleHeat[i] = make([]float64, len(floor.Obstacles))
+	}
+	var maxCount, maxHeat float64
+	for _, freq := range freqs {
+		if freq.Floor < 0 || freq.Floor >= len(layout.Floors) {
+			continue
+		}
+		maxCount = math.Max(maxCount, float64(freq.Count))
+		floor := layout.Floors[freq.Floor]
+		if idx := nearestObstacle(floor, freq.Zone.Location); idx != -1 {
+			heat := obstacleHeat[freq.Floor]
+			heat[idx] += float64(freq.Count)
+			maxHeat = math.Max(maxHeat, heat[idx])
+		}
+	}
+
+	fontSize := t.FontSize(layout)
+	radius := t.MarkerRadius(layout)
+	heatColor := cssColor(t.Heat)
+	MultiFloorLoopViewport(layout, v, func(f *optishop.Floor, x, y float64) {
+		floorIdx := layout.FloorIndex(f)
+		canvas.Group(`class="floor-group"`, fmt.Sprintf(`data-floor="%d"`, floorIdx))
+		t.DrawFloorPolygons(canvas, f, x, y)
+
+		canvas.Group(`class="heatmap"`)
+		for i, heat := range obstacleHeat[floorIdx] {
+			if heat > 0 {
+				DrawPolygon(canvas, f.Obstacles[i], x, y, `class="heat-obstacle"`,
+					fmt.Sprintf("fill: %s; fill-opacity: %.3f", heatColor, heatOpacity(heat/maxHeat)))
+			}
+		}
+		for _, freq := range freqs {
+			if freq.Floor != floorIdx {
+				continue
+			}
+			frac := float64(freq.Count) / maxCount
+			label := fmt.Sprintf("%s: %d", freq.Zone.Name, freq.Count)
+			canvas.Group(`class="heat-zone"`,
+				fmt.Sprintf(`data-zone="%s"`, html.EscapeString(freq.Zone.Name)),
+				fmt.Sprintf(`data-count="%d"`, freq.Count))
+			canvas.Title(label)
+			canvas.Circle(freq.Zone.Location.X+x, freq.Zone.Location.Y+y, radius*(1+2*frac),
+				fmt.Sprintf("fill: %s; fill-opacity: %.3f", heatColor, heatOpacity(frac)))
+			canvas.Gend()
+		}
+		canvas.Gend()
+
+		t.DrawFloorLabels(canvas, f, x, y, fontSize)
+		canvas.Gend()
+	})
+}
+
+// heatOpacity maps a fraction of the maximum frequency to
+// an opacity, so that even rare zones are visible.
+func heatOpacity(frac float64) float64 {
+	return 0.15 + 0.7*frac
+}
+
+// nearestObstacle finds the index of the obstacle closest
+// to a point, or returns -1 if no obstacle is within the
+// heatmap radius.
+func nearestObstacle(floor *optishop.Floor, p optishop.Point) int {
+	_, _, w, h := floor.Bounds.Bounds()
+	maxDist := math.Max(w, h) * HeatmapRadiusFrac
+	res := -1
+	for i, obstacle := range floor.Obstacles {
+		if d := polygonDistance(obstacle, p); d <= maxDist {
+			res = i
+			maxDist = d
+		}
+	}
+	return res
+}
+
+// polygonDistance computes the distance from a point to
+// the boundary of a polygon.
+func polygonDistance(poly optishop.Polygon, p optishop.Point) float64 {
+	res := math.Inf(1)
+	for i, p1 := range poly {
+		p2 := poly[(i+1)%len(poly)]
+		res = math.Min(res, segmentDistance(p.X, p.Y, p1.X, p1.Y, p2.X, p2.Y))
+	}
+	return res
+}
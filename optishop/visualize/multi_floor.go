@@ -1,7 +1,6 @@
 package visualize
 
 import (
-	"fmt"
 	"math"
 
 	svg "github.com/ajstarks/svgo/float"
@@ -12,18 +11,6 @@ import (
 // are with respect to the size of the layout.
 const MarginFrac = 0.1
 
-// FontSizeFrac controls how big the department labels are
-// with respect to the size of the layout.
-const FontSizeFrac = 1.0 / 150.0
-
-// SpecificLabelSizeFrac controls how big the aisle labels are with
-// respect to the size of the department labels.
-const SpecificLabelSizeFrac = 0.5
-
-// PathFrac controls how thick paths are with respect to
-// the size of the layout.
-const PathFrac = 0.002
-
 // MultiFloorGeometry computes the total width and height
 // of a rendered layout, when all the floors are rendered
 // on the same image.
@@ -57,34 +44,32 @@ func MultiFloorLoop(layout *optishop.Layout, f func(f *optishop.Floor, x, y floa
 	}
 }
 
-// DrawFloors draws every floor of a layout.
-func DrawFloors(canvas *svg.SVG, layout *optishop.Layout) {
-	width, _, _ := MultiFloorGeometry(layout)
-	fontSize := width * FontSizeFrac
-	MultiFloorLoop(layout, func(f *optishop.Floor, x, y float64) {
-		DrawFloor(canvas, f, x, y, fontSize)
+// MultiFloorLoopViewport is like MultiFloorLoop, but it
+// only calls f for floors which are visible in the
+// viewport.
+func MultiFloorLoopViewport(layout *optishop.Layout, v Viewport,
+	f func(f *optishop.Floor, x, y float64)) {
+	MultiFloorLoop(layout, func(floor *optishop.Floor, x, y float64) {
+		if FloorViewport(layout, layout.FloorIndex(floor)).Intersects(v) {
+			f(floor, x, y)
+		}
 	})
 }
 
+// DrawFloors draws every floor of a layout using the
+// DefaultTheme.
+func DrawFloors(canvas *svg.SVG, layout *optishop.Layout) {
+	DefaultTheme.DrawFloors(canvas, layout)
+}
+
+// DrawFloorsViewport draws the floors of a layout which
+// are visible in a viewport using the DefaultTheme.
+func DrawFloorsViewport(canvas *svg.SVG, layout *optishop.Layout, v Viewport) {
+	DefaultTheme.DrawFloorsViewport(canvas, layout, v)
+}
+
 // DrawFloorPath traces out a path on a multi-floor
-// rendering.
+// rendering using the DefaultTheme.
 func DrawFloorPath(canvas *svg.SVG, layout *optishop.Layout, path optishop.FloorPath) {
-	width, _, _ := MultiFloorGeometry(layout)
-	MultiFloorLoop(layout, func(f *optishop.Floor, x, y float64) {
-		for _, part := range path {
-			if layout.Floors[part.Floor] != f {
-				continue
-			}
-			var pathX, pathY []float64
-			for _, p := range part.Path {
-				pathX = append(pathX, p.X+x)
-				pathY = append(pathY, p.Y+y)
-			}
-			lineWidth := fmt.Sprintf("%.3f", width*PathFrac)
-			canvas.Polyline(pathX, pathY,
-				"stroke-width: "+lineWidth+"px; stroke: #65bcd4; fill: none")
-			canvas.Circle(pathX[len(pathX)-1], pathY[len(pathY)-1], width*PathFrac*2,
-				"fill: #65bcd4")
-		}
-	})
+	DefaultTheme.DrawFloorPath(canvas, layout, path)
 }
@@ -0,0 +1,326 @@
+package visualize
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+// MaxRasterPixels limits the number of pixels in a
+// RasterCanvas, so that extreme aspect ratios cannot
+// allocate huge images.
+const MaxRasterPixels = 4000 * 4000
+
+// A RasterCanvas draws layouts onto an image, for clients
+// which cannot display SVGs.
+//
+// Drawing methods take the same coordinates as the SVG
+// drawing functions, and the Viewport determines which
+// part of the rendering ends up in the image.
+type RasterCanvas struct {
+	Image    *image.RGBA
+	Viewport Viewport
+	Theme    *Theme
+}
+
+// NewRasterCanvas creates a RasterCanvas for a viewport,
+// where the image is width pixels wide and the height is
+// determined by the aspect ratio of the viewport.
+//
+// If theme is nil, DefaultTheme is used.
+//
+// Returns an error if the image would have more than
+// MaxRasterPixels pixels.
+func NewRasterCanvas(v Viewport, width int, theme *Theme) (*RasterCanvas, error) {
+	if theme == nil {
+		theme = DefaultTheme
+	}
+	if width <= 0 || !(v.Width > 0) || !(v.Height > 0) {
+		return nil, errors.New("new raster canvas: invalid image size")
+	}
+	fHeight := math.Ceil(float64(width) * v.Height / v.Width)
+	if fHeight*float64(width) > MaxRasterPixels {
+		return nil, errors.New("new raster canvas: image is too large")
+	}
+	height := int(fHeight)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i] = theme.Background.R
+		img.Pix[i+1] = theme.Background.G
+		img.Pix[i+2] = theme.Background.B
+		img.Pix[i+3] = theme.Background.A
+	}
+	return &RasterCanvas{Image: img, Viewport: v, Theme: theme}, nil
+}
+
+// EncodePNG writes the image as a PNG.
+func (r *RasterCanvas) EncodePNG(w io.Writer) error {
+	return png.Encode(w, r.Image)
+}
+
+// DrawFloors draws the floors of a layout which are
+// visible in the viewport.
+func (r *RasterCanvas) DrawFloors(layout *optishop.Layout) {
+	fontSize := r.Theme.FontSize(layout)
+	MultiFloorLoopViewport(layout, r.Viewport, func(f *optishop.Floor, x, y float64) {
+		r.DrawFloor(f, x, y, fontSize)
+	})
+}
+
+// DrawFloor draws all of the objects on a floor.
+func (r *RasterCanvas) DrawFloor(floor *optishop.Floor, xOff, yOff, fontSize float64) {
+	r.DrawFloorPolygons(floor, xOff, yOff)
+	r.DrawZoneLabels(floor.Zones, xOff, yOff, fontSize)
+}
+
+// DrawFloorPolygons draws all of the objects on a floor
+// except for the labels.
+func (r *RasterCanvas) DrawFloorPolygons(floor *optishop.Floor, xOff, yOff float64) {
+	r.FillPolygon(floor.Bounds, xOff, yOff, r.Theme.Floor)
+	r.outlinePolygon(floor, floor.Bounds, xOff, yOff)
+	for _, nonPref := range floor.NonPreferred {
+		if nonPref.Visible {
+			r.FillPolygon(nonPref.Bounds, xOff, yOff, r.Theme.NonPreferred)
+		}
+	}
+	for _, obstacle := range floor.Obstacles {
+		r.FillPolygon(obstacle, xOff, yOff, r.Theme.Obstacle)
+		r.outlinePolygon(floor, obstacle, xOff, yOff)
+	}
+}
+
+func (r *RasterCanvas) outlinePolygon(floor *optishop.Floor, poly optishop.Polygon,
+	xOff, yOff float64) {
+	if r.Theme.OutlineFrac == 0 {
+		return
+	}
+	closed := append(optishop.Path{}, poly.Dedup()...)
+	closed = append(closed, closed[0])
+	r.StrokePath(closed, xOff, yOff, r.Theme.outlineWidth(floor), r.Theme.Outline)
+}
+
+// DrawZoneLabels draws the labels for specified zones.
+func (r *RasterCanvas) DrawZoneLabels(zones []*optishop.Zone, xOff, yOff, fontSize float64) {
+	for _, zone := range zones {
+		fs := fontSize
+		if zone.Specific {
+			fs *= r.Theme.SpecificLabelSizeFrac
+		}
+		r.Text(zone.Location.X+xOff, zone.Location.Y+yOff, zone.Name, fs, r.Theme.Label)
+	}
+}
+
+// DrawFloorPath traces out a path on a multi-floor
+// rendering.
+func (r *RasterCanvas) DrawFloorPath(layout *optishop.Layout, path optishop.FloorPath) {
+	pathWidth := r.Theme.PathWidth(layout)
+	for _, part := range path {
+		if len(part.Path) == 0 {
+			continue
+		}
+		x, y := FloorOffset(layout, part.Floor)
+		r.StrokePath(part.Path, x, y, pathWidth, r.Theme.Path)
+		end := part.Path[len(part.Path)-1]
+		r.FillCircle(end.X+x, end.Y+y, pathWidth*2, r.Theme.Path)
+	}
+}
+
+// DrawStops draws a numbered marker for each stop.
+func (r *RasterCanvas) DrawStops(layout *optishop.Layout, stops []*Stop) {
+	radius := r.Theme.MarkerRadius(layout)
+	for i, p := range StopPositions(layout, stops, radius) {
+		r.drawMarker(p.X, p.Y, radius, stops[i].Number)
+	}
+}
+
+// DrawPathArrows draws arrows along a path indicating the
+// direction of travel.
+func (r *RasterCanvas) DrawPathArrows(layout *optishop.Layout, path optishop.FloorPath) {
+	for _, arrow := range r.Theme.pathArrows(layout, path) {
+		r.FillPolygon(arrow, 0, 0, r.Theme.Path)
+	}
+}
+
+// DrawPathPortals draws an icon for each portal used by a
+// path.
+func (r *RasterCanvas) DrawPathPortals(layout *optishop.Layout, path optishop.FloorPath) {
+	radius := r.Theme.MarkerRadius(layout)
+	for _, icon := range PathPortals(layout, path) {
+		p := icon.Location
+		r.FillRect(p.X-radius, p.Y-radius, radius*2, radius*2, r.Theme.Portal)
+		r.Text(p.X, p.Y, portalSymbol(icon.Type), radius, r.Theme.MarkerText)
+	}
+}
+
+// DrawLegend draws a legend mapping stop numbers to labels
+// in the top-left corner of the viewport.
+func (r *RasterCanvas) DrawLegend(stops []*Stop) {
+	if len(stops) == 0 {
+		return
+	}
+	labels := legendLabels(stops)
+	g := newLegendGeometry(r.Viewport, labels)
+	r.FillRect(g.X, g.Y, g.Width, g.Height, r.Theme.Floor)
+	r.StrokePath(optishop.Path{
+		{X: g.X, Y: g.Y},
+		{X: g.X + g.Width, Y: g.Y},
+		{X: g.X + g.Width, Y: g.Y + g.Height},
+		{X: g.X, Y: g.Y + g.Height},
+		{X: g.X, Y: g.Y},
+	}, 0, 0, g.FontSize/20, r.Theme.Label)
+	for i, stop := range stops {
+		x, y := g.MarkerCenter(i)
+		r.drawMarker(x, y, g.RowHeight*0.4, stop.Number)
+		x, y = g.TextStart(i)
+		textWidth := float64(len([]rune(labels[i]))) * g.FontSize * legendCharWidth
+		r.Text(x+textWidth/2, y, labels[i], g.FontSize, r.Theme.Label)
+	}
+}
+
+func (r *RasterCanvas) drawMarker(x, y, radius float64, number int) {
+	r.FillCircle(x, y, radius, r.Theme.Marker)
+	r.Text(x, y, strconv.Itoa(number), radius*1.2, r.Theme.MarkerText)
+}
+
+// FillRect fills in an axis-aligned rectangle.
+func (r *RasterCanvas) FillRect(x, y, width, height float64, c color.RGBA) {
+	x1, y1 := r.pixelCoords(x, y)
+	x2, y2 := r.pixelCoords(x+width, y+height)
+	r.fillRegion(x1, y1, x2, y2, c, nil)
+}
+
+// FillPolygon fills in a polygon using the even-odd rule.
+//
+// All points are offset by (xOff, yOff).
+func (r *RasterCanvas) FillPolygon(poly optishop.Polygon, xOff, yOff float64, c color.RGBA) {
+	points := make([]optishop.Point, 0, len(poly))
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, p := range poly.Dedup() {
+		px, py := r.pixelCoords(p.X+xOff, p.Y+yOff)
+		points = append(points, optishop.Point{X: px, Y: py})
+		minY = math.Min(minY, py)
+		maxY = math.Max(maxY, py)
+	}
+	bounds := r.Image.Bounds()
+	startRow := int(math.Max(0, math.Floor(minY)))
+	endRow := int(math.Min(float64(bounds.Dy()), math.Ceil(maxY)))
+
+	var crossings []float64
+	for row := startRow; row < endRow; row++ {
+		y := float64(row) + 0.5
+		crossings = crossings[:0]
+		for i, p1 := range points {
+			p2 := points[(i+1)%len(points)]
+			if (p1.Y <= y) != (p2.Y <= y) {
+				frac := (y - p1.Y) / (p2.Y - p1.Y)
+				crossings = append(crossings, p1.X+frac*(p2.X-p1.X))
+			}
+		}
+		sort.Float64s(crossings)
+		for i := 0; i+1 < len(crossings); i += 2 {
+			start := int(math.Max(0, math.Ceil(crossings[i]-0.5)))
+			end := int(math.Min(float64(bounds.Dx()), math.Ceil(crossings[i+1]-0.5)))
+			for col := start; col < end; col++ {
+				r.Image.SetRGBA(col, row, c)
+			}
+		}
+	}
+}
+
+// StrokePath draws a line along a path with the given
+// thickness.
+//
+// All points are offset by (xOff, yOff).
+func (r *RasterCanvas) StrokePath(path optishop.Path, xOff, yOff, width float64,
+	c color.RGBA) {
+	radius := width * r.scale() / 2
+	for i := 1; i < len(path); i++ {
+		x1, y1 := r.pixelCoords(path[i-1].X+xOff, path[i-1].Y+yOff)
+		x2, y2 := r.pixelCoords(path[i].X+xOff, path[i].Y+yOff)
+		r.fillRegion(math.Min(x1, x2)-radius, math.Min(y1, y2)-radius,
+			math.Max(x1, x2)+radius, math.Max(y1, y2)+radius, c,
+			func(x, y float64) bool {
+				return segmentDistance(x, y, x1, y1, x2, y2) <= radius
+			})
+	}
+}
+
+// FillCircle fills in a circle.
+func (r *RasterCanvas) FillCircle(x, y, radius float64, c color.RGBA) {
+	cx, cy := r.pixelCoords(x, y)
+	radius *= r.scale()
+	r.fillRegion(cx-radius, cy-radius, cx+radius, cy+radius, c, func(x, y float64) bool {
+		return math.Pow(x-cx, 2)+math.Pow(y-cy, 2) <= radius*radius
+	})
+}
+
+// Text draws text centered at a point using a built-in
+// bitmap font, where fontSize is the height of the text.
+func (r *RasterCanvas) Text(x, y float64, text string, fontSize float64, c color.RGBA) {
+	dotSize := math.Max(1, math.Round(fontSize*r.scale()/(glyphHeight+1)))
+	runes := []rune(text)
+	textWidth := dotSize * float64(len(runes)*(glyphWidth+1)-1)
+	textHeight := dotSize * glyphHeight
+
+	cx, cy := r.pixelCoords(x, y)
+	left := math.Round(cx - textWidth/2)
+	top := math.Round(cy - textHeight/2)
+	for i, ch := range runes {
+		g := glyph(ch)
+		glyphLeft := left + float64(i*(glyphWidth+1))*dotSize
+		for row, bits := range g {
+			for col := 0; col < glyphWidth; col++ {
+				if bits&(1<<uint(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				dotX := glyphLeft + float64(col)*dotSize
+				dotY := top + float64(row)*dotSize
+				r.fillRegion(dotX, dotY, dotX+dotSize, dotY+dotSize, c, nil)
+			}
+		}
+	}
+}
+
+// fillRegion sets every pixel in a rectangle whose center
+// satisfies the predicate f, or every pixel if f is nil.
+func (r *RasterCanvas) fillRegion(minX, minY, maxX, maxY float64, c color.RGBA,
+	f func(x, y float64) bool) {
+	bounds := r.Image.Bounds()
+	startX := int(math.Max(0, math.Floor(minX)))
+	startY := int(math.Max(0, math.Floor(minY)))
+	endX := int(math.Min(float64(bounds.Dx()), math.Ceil(maxX)))
+	endY := int(math.Min(float64(bounds.Dy()), math.Ceil(maxY)))
+	for row := startY; row < endY; row++ {
+		for col := startX; col < endX; col++ {
+			if f == nil || f(float64(col)+0.5, float64(row)+0.5) {
+				r.Image.SetRGBA(col, row, c)
+			}
+		}
+	}
+}
+
+func (r *RasterCanvas) scale() float64 {
+	return float64(r.Image.Bounds().Dx()) / r.Viewport.Width
+}
+
+func (r *RasterCanvas) pixelCoords(x, y float64) (float64, float64) {
+	s := r.scale()
+	return (x - r.Viewport.X) * s, (y - r.Viewport.Y) * s
+}
+
+func segmentDistance(x, y, x1, y1, x2, y2 float64) float64 {
+	dx, dy := x2-x1, y2-y1
+	normSquared := dx*dx + dy*dy
+	frac := 0.0
+	if normSquared > 0 {
+		frac = math.Max(0, math.Min(1, ((x-x1)*dx+(y-y1)*dy)/normSquared))
+	}
+	return math.Hypot(x-(x1+frac*dx), y-(y1+frac*dy))
+}
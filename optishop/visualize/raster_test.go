@@ -0,0 +1,56 @@
+package visualize
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+func TestRasterCanvasFillPolygon(t *testing.T) {
+	canvas := testRasterCanvas(t)
+	fill := color.RGBA{R: 255, A: 255}
+	canvas.FillPolygon(optishop.Polygon{{X: 1, Y: 1}, {X: 5, Y: 1}, {X: 5, Y: 4}, {X: 1, Y: 4}},
+		1, 1, fill)
+	checkRasterPixels(t, canvas, fill, func(x, y int) bool {
+		return x >= 2 && x < 6 && y >= 2 && y < 5
+	})
+}
+
+func TestRasterCanvasStrokePath(t *testing.T) {
+	canvas := testRasterCanvas(t)
+	stroke := color.RGBA{B: 255, A: 255}
+	canvas.StrokePath(optishop.Path{{X: 1, Y: 8}, {X: 9, Y: 8}}, 0, 0, 2, stroke)
+	checkRasterPixels(t, canvas, stroke, func(x, y int) bool {
+		return y == 7 || y == 8
+	})
+}
+
+func TestNewRasterCanvasTooLarge(t *testing.T) {
+	v := Viewport{Width: 1, Height: 1e6}
+	if _, err := NewRasterCanvas(v, 1000, nil); err == nil {
+		t.Error("expected error for huge canvas")
+	}
+}
+
+func testRasterCanvas(t *testing.T) *RasterCanvas {
+	canvas, err := NewRasterCanvas(Viewport{Width: 10, Height: 10}, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return canvas
+}
+
+func checkRasterPixels(t *testing.T, canvas *RasterCanvas, c color.RGBA,
+	expected func(x, y int) bool) {
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			actual := canvas.Image.RGBAAt(x, y)
+			if expected(x, y) && actual != c {
+				t.Errorf("pixel (%d, %d) should be filled but is %v", x, y, actual)
+			} else if !expected(x, y) && actual != DefaultTheme.Background {
+				t.Errorf("pixel (%d, %d) should be background but is %v", x, y, actual)
+			}
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package visualize
+
+import (
+	"fmt"
+	"html"
+	"math"
+
+	svg "github.com/ajstarks/svgo/float"
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+const (
+	// ArrowSpacingFrac controls how far apart direction
+	// arrows are along a path with respect to the size of
+	// the layout.
+	ArrowSpacingFrac = 0.04
+
+	// ArrowSizeFrac controls how big direction arrows are
+	// with respect to the width of a path.
+	ArrowSizeFrac = 3
+
+	// LegendFontFrac controls how big legend text is with
+	// respect to the width of the viewport.
+	LegendFontFrac = 1.0 / 40.0
+
+	// LegendMaxChars is the maximum length of a label in a
+	// legend before it is truncated.
+	LegendMaxChars = 32
+
+	// legendCharWidth is the approximate width of a
+	// character with respect to the font size.
+	legendCharWidth = 0.75
+)
+
+// A Stop is a numbered point on a route where an item is
+// picked up.
+type Stop struct {
+	Number   int
+	Floor    int
+	Location optishop.Point
+	Label    string
+}
+
+// StopPositions computes the rendered coordinates of the
+// markers for stops.
+//
+// Markers for stops at the same location are spread out
+// horizontally so that they do not overlap.
+func StopPositions(layout *optishop.Layout, stops []*Stop, radius float64) []optishop.Point {
+	type stopKey struct {
+		Floor    int
+		Location optishop.Point
+	}
+	groups := map[stopKey][]int{}
+	var keys []stopKey
+	for i, stop := range stops {
+		key := stopKey{Floor: stop.Floor, Location: stop.Location}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+	res := make([]optishop.Point, len(stops))
+	for _, key := range keys {
+		group := groups[key]
+		x, y := FloorOffset(layout, key.Floor)
+		for j, idx := range group {
+			shift := (float64(j) - float64(len(group)-1)/2) * radius * 2.2
+			res[idx] = optishop.Point{
+				X: key.Location.X + x + shift,
+				Y: key.Location.Y + y,
+			}
+		}
+	}
+	return res
+}
+
+// PathArrows computes arrowheads in rendered coordinates
+// which point in the direction of travel along a path.
+//
+// Arrows are placed every spacing units along each step of
+// the path, and at least one arrow is placed on every step
+// with a non-zero length.
+func PathArrows(layout *optishop.Layout, path optishop.FloorPath,
+	spacing, size float64) []optishop.Polygon {
+	var res []optishop.Polygon
+	for _, step := range path {
+		length := step.Path.Length()
+		if length == 0 {
+			continue
+		}
+		x, y := FloorOffset(layout, step.Floor)
+		offset := optishop.Point{X: x, Y: y}
+		numArrows := math.Max(1, math.Floor(length/spacing))
+		stride := length / numArrows
+		for dist := stride / 2; dist < length; dist += stride {
+			p, dir := pathPointAt(step.Path, dist)
+			p = optishop.Point{X: p.X + offset.X, Y: p.Y + offset.Y}
+			normal := optishop.Point{X: -dir.Y, Y: dir.X}
+			res = append(res, optishop.Polygon{
+				{X: p.X + dir.X*size, Y: p.Y + dir.Y*size},
+				{X: p.X - dir.X*size + normal.X*size*0.8, Y: p.Y - dir.Y*size + normal.Y*size*0.8},
+				{X: p.X - dir.X*size*0.4, Y: p.Y - dir.Y*size*0.4},
+				{X: p.X - dir.X*size - normal.X*size*0.8, Y: p.Y - dir.Y*size - normal.Y*size*0.8},
+			})
+		}
+	}
+	return res
+}
+
+// pathPointAt finds the point which is dist units along a
+// path, and the unit direction of the path at that point.
+func pathPointAt(path optishop.Path, dist float64) (optishop.Point, optishop.Point) {
+	var lastDir optishop.Point
+	for i := 1; i < len(path); i++ {
+		p1, p2 := path[i-1], path[i]
+		segLength := p1.Distance(p2)
+		if segLength == 0 {
+			continue
+		}
+		lastDir = optishop.Point{X: (p2.X - p1.X) / segLength, Y: (p2.Y - p1.Y) / segLength}
+		if dist <= segLength {
+			return optishop.Point{X: p1.X + lastDir.X*dist, Y: p1.Y + lastDir.Y*dist}, lastDir
+		}
+		dist -= segLength
+	}
+	return path[len(path)-1], lastDir
+}
+
+// A PortalIcon is a portal used by a route, positioned in
+// rendered coordinates.
+type PortalIcon struct {
+	Location optishop.Point
+	Type     optishop.PortalType
+}
+
+// PathPortals finds the portals which are used by a path,
+// including both the portal where each floor is left and
+// the portal where the next floor is entered.
+func PathPortals(layout *optishop.Layout, path optishop.FloorPath) []PortalIcon {
+	var res []PortalIcon
+	addPortal := func(floor, id int) {
+		portal := layout.Portal(id)
+		if portal == nil {
+			return
+		}
+		x, y := FloorOffset(layout, floor)
+		res = append(res, PortalIcon{
+			Location: optishop.Point{X: portal.Location.X + x, Y: portal.Location.Y + y},
+			Type:     portal.Type,
+		})
+	}
+	for i := 0; i+1 < len(path); i++ {
+		addPortal(path[i].Floor, path[i].SourcePortal)
+		addPortal(path[i+1].Floor, path[i].DestPortal)
+	}
+	return res
+}
+
+// portalSymbol gets the short text drawn on a portal icon.
+func portalSymbol(t optishop.PortalType) string {
+	switch t {
+	case optishop.Elevator:
+		return "EL"
+	case optishop.Escalator:
+		return "ES"
+	case optishop.Stairs:
+		return "ST"
+	}
+	return "?"
+}
+
+// portalName gets a human-readable name for a portal type.
+func portalName(t optishop.PortalType) string {
+	switch t {
+	case optishop.Elevator:
+		return "Elevator"
+	case optishop.Escalator:
+		return "Escalator"
+	case optishop.Stairs:
+		return "Stairs"
+	}
+	return "Portal"
+}
+
+// legendGeometry describes where a legend is drawn.
+type legendGeometry struct {
+	FontSize  float64
+	Padding   float64
+	RowHeight float64
+	X         float64
+	Y         float64
+	Width     float64
+	Height    float64
+}
+
+func newLegendGeometry(v Viewport, labels []string) *legendGeometry {
+	fontSize := math.Min(v.Width*LegendFontFrac, v.Height/(1.5*float64(len(labels)+1)))
+	var maxChars int
+	for _, label := range labels {
+		if n := len([]rune(label)); n > maxChars {
+			maxChars = n
+		}
+	}
+	g := &legendGeometry{
+		FontSize:  fontSize,
+		Padding:   fontSize / 2,
+		RowHeight: fontSize * 1.5,
+	}
+	g.X = v.X + g.Padding
+	g.Y = v.Y + g.Padding
+	g.Width = g.Padding*2 + g.RowHeight + float64(maxChars)*fontSize*legendCharWidth
+	g.Height = g.Padding*2 + g.RowHeight*float64(len(labels))
+	return g
+}
+
+// MarkerCenter gets the center of the stop marker in the
+// given row of the legend.
+func (g *legendGeometry) MarkerCenter(row int) (float64, float64) {
+	return g.X + g.Padding + g.RowHeight/2,
+		g.Y + g.Padding + g.RowHeight*(float64(row)+0.5)
+}
+
+// TextStart gets the left side and vertical center of the
+// text in the given row of the legend.
+func (g *legendGeometry) TextStart(row int) (float64, float64) {
+	_, y := g.MarkerCenter(row)
+	return g.X + g.Padding + g.RowHeight + g.FontSize/2, y
+}
+
+// legendLabels gets the (possibly truncated) text of each
+// row of a legend.
+func legendLabels(stops []*Stop) []string {
+	res := make([]string, len(stops))
+	for i, stop := range stops {
+		label := []rune(stop.Label)
+		if len(label) > LegendMaxChars {
+			label = append(label[:LegendMaxChars-3], []rune("...")...)
+		}
+		res[i] = string(label)
+	}
+	return res
+}
+
+// MarkerRadius gets the radius of stop markers and portal
+// icons for a multi-floor rendering of a layout.
+func (t *Theme) MarkerRadius(layout *optishop.Layout) float64 {
+	width, _, _ := MultiFloorGeometry(layout)
+	return width * t.MarkerFrac
+}
+
+// DrawStops draws a numbered marker for each stop.
+func (t *Theme) DrawStops(canvas *svg.SVG, layout *optishop.Layout, stops []*Stop) {
+	radius := t.MarkerRadius(layout)
+	for i, p := range StopPositions(layout, stops, radius) {
+		stop := stops[i]
+		label := fmt.Sprintf("Stop %d: %s", stop.Number, stop.Label)
+		canvas.Group(`class="stop-marker"`, fmt.Sprintf(`data-stop="%d"`, stop.Number),
+			`role="img"`, fmt.Sprintf(`aria-label="%s"`, html.EscapeString(label)))
+		canvas.Title(label)
+		t.drawMarker(canvas, p.X, p.Y, radius, stop.Number)
+		canvas.Gend()
+	}
+}
+
+// DrawPathArrows draws arrows along a path indicating the
+// direction of travel.
+func (t *Theme) DrawPathArrows(canvas *svg.SVG, layout *optishop.Layout,
+	path optishop.FloorPath) {
+	canvas.Group(`class="route-arrows"`, `aria-hidden="true"`)
+	for _, arrow := range t.pathArrows(layout, path) {
+		DrawPolygon(canvas, arrow, 0, 0, "fill: "+cssColor(t.Path))
+	}
+	canvas.Gend()
+}
+
+// DrawPathPortals draws an icon for each portal used by a
+// path.
+func (t *Theme) DrawPathPortals(canvas *svg.SVG, layout *optishop.Layout,
+	path optishop.FloorPath) {
+	radius := t.MarkerRadius(layout)
+	for _, icon := range PathPortals(layout, path) {
+		name := portalName(icon.Type)
+		canvas.Group(`class="portal-icon"`, fmt.Sprintf(`data-portal-type="%s"`, icon.Type),
+			`role="img"`, fmt.Sprintf(`aria-label="%s"`, name))
+		canvas.Title(name)
+		canvas.Roundrect(icon.Location.X-radius, icon.Location.Y-radius, radius*2, radius*2,
+			radius/3, radius/3, "fill: "+cssColor(t.Portal))
+		canvas.Text(icon.Location.X, icon.Location.Y, portalSymbol(icon.Type),
+			fmt.Sprintf("text-anchor: middle; dominant-baseline: central; "+
+				"font-size: %.3fpx; font-weight: bold; fill: %s", radius, cssColor(t.MarkerText)))
+		canvas.Gend()
+	}
+}
+
+// DrawLegend draws a legend mapping stop numbers to labels
+// in the top-left corner of the viewport.
+func (t *Theme) DrawLegend(canvas *svg.SVG, v Viewport, stops []*Stop) {
+	if len(stops) == 0 {
+		return
+	}
+	labels := legendLabels(stops)
+	g := newLegendGeometry(v, labels)
+	canvas.Group(`class="route-legend"`, `role="list"`, `aria-label="Legend"`)
+	canvas.Rect(g.X, g.Y, g.Width, g.Height, `class="legend-background"`,
+		fmt.Sprintf("fill: %s; fill-opacity: 0.9; stroke: %s; stroke-width: %.3fpx",
+			cssColor(t.Floor), cssColor(t.Label), g.FontSize/20))
+	for i, stop := range stops {
+		canvas.Group(`role="listitem"`, fmt.Sprintf(`data-stop="%d"`, stop.Number))
+		x, y := g.MarkerCenter(i)
+		t.drawMarker(canvas, x, y, g.RowHeight*0.4, stop.Number)
+		x, y = g.TextStart(i)
+		canvas.Text(x, y, labels[i], fmt.Sprintf("dominant-baseline: central; "+
+			"font-size: %.3fpx; fill: %s", g.FontSize, cssColor(t.Label)))
+		canvas.Gend()
+	}
+	canvas.Gend()
+}
+
+func (t *Theme) pathArrows(layout *optishop.Layout, path optishop.FloorPath) []optishop.Polygon {
+	width, _, _ := MultiFloorGeometry(layout)
+	return PathArrows(layout, path, width*ArrowSpacingFrac, t.PathWidth(layout)*ArrowSizeFrac)
+}
+
+func (t *Theme) drawMarker(canvas *svg.SVG, x, y, radius float64, number int) {
+	canvas.Circle(x, y, radius, "fill: "+cssColor(t.Marker))
+	canvas.Text(x, y, fmt.Sprint(number), fmt.Sprintf("text-anchor: middle; "+
+		"dominant-baseline: central; font-size: %.3fpx; font-weight: bold; fill: %s",
+		radius*1.2, cssColor(t.MarkerText)))
+}
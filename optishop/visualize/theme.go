@@ -0,0 +1,257 @@
+package visualize
+
+import (
+	"fmt"
+	"html"
+	"image/color"
+	"math"
+
+	svg "github.com/ajstarks/svgo/float"
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+// A Theme controls the appearance of renderings.
+//
+// Sizes are fractions of the size of the rendered layout,
+// so that themes work for layouts of any scale.
+type Theme struct {
+	Background   color.RGBA
+	Floor        color.RGBA
+	NonPreferred color.RGBA
+	Obstacle     color.RGBA
+	Outline      color.RGBA
+	Path         color.RGBA
+	Label        color.RGBA
+	Marker       color.RGBA
+	MarkerText   color.RGBA
+	Portal       color.RGBA
+	Heat         color.RGBA
+
+	// FontSizeFrac controls how big the department labels
+	// are with respect to the size of the layout.
+	FontSizeFrac float64
+
+	// SpecificLabelSizeFrac controls how big the aisle
+	// labels are with respect to the size of the
+	// department labels.
+	SpecificLabelSizeFrac float64
+
+	// PathFrac controls how thick paths are with respect
+	// to the size of the layout.
+	PathFrac float64
+
+	// OutlineFrac controls how thick the outlines of floors
+	// and obstacles are with respect to the size of the
+	// floor.
+	// If 0, no outlines are drawn.
+	OutlineFrac float64
+
+	// MarkerFrac controls how big stop markers and portal
+	// icons are with respect to the size of the layout.
+	MarkerFrac float64
+}
+
+// DefaultTheme is the standard appearance of renderings.
+var DefaultTheme = &Theme{
+	Background:   color.RGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff},
+	Floor:        color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	NonPreferred: color.RGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff},
+	Obstacle:     color.RGBA{R: 0xd5, G: 0xd5, B: 0xd5, A: 0xff},
+	Path:         color.RGBA{R: 0x65, G: 0xbc, B: 0xd4, A: 0xff},
+	Label:        color.RGBA{A: 0xff},
+	Marker:       color.RGBA{R: 0xd4, G: 0x3f, B: 0x3a, A: 0xff},
+	MarkerText:   color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	Portal:       color.RGBA{R: 0x6a, G: 0x4c, B: 0x93, A: 0xff},
+	Heat:         color.RGBA{R: 0xe4, G: 0x57, B: 0x2e, A: 0xff},
+
+	FontSizeFrac:          1.0 / 150.0,
+	SpecificLabelSizeFrac: 0.5,
+	PathFrac:              0.002,
+	MarkerFrac:            0.008,
+}
+
+// HighContrastTheme uses black outlines, thick paths, and
+// large labels for users with low vision.
+var HighContrastTheme = &Theme{
+	Background:   color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	Floor:        color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	NonPreferred: color.RGBA{R: 0xe0, G: 0xe0, B: 0xe0, A: 0xff},
+	Obstacle:     color.RGBA{R: 0x99, G: 0x99, B: 0x99, A: 0xff},
+	Outline:      color.RGBA{A: 0xff},
+	Path:         color.RGBA{R: 0x00, G: 0x33, B: 0xcc, A: 0xff},
+	Label:        color.RGBA{A: 0xff},
+	Marker:       color.RGBA{A: 0xff},
+	MarkerText:   color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	Portal:       color.RGBA{R: 0xcc, G: 0x00, B: 0x00, A: 0xff},
+	Heat:         color.RGBA{R: 0xcc, G: 0x00, B: 0x00, A: 0xff},
+
+	FontSizeFrac:          1.0 / 100.0,
+	SpecificLabelSizeFrac: 0.7,
+	PathFrac:              0.005,
+	OutlineFrac:           0.002,
+	MarkerFrac:            0.012,
+}
+
+// DarkTheme uses light content on a dark background.
+var DarkTheme = &Theme{
+	Background:   color.RGBA{R: 0x12, G: 0x12, B: 0x12, A: 0xff},
+	Floor:        color.RGBA{R: 0x2b, G: 0x2b, B: 0x2b, A: 0xff},
+	NonPreferred: color.RGBA{R: 0x36, G: 0x36, B: 0x36, A: 0xff},
+	Obstacle:     color.RGBA{R: 0x55, G: 0x55, B: 0x55, A: 0xff},
+	Path:         color.RGBA{R: 0x7f, G: 0xd6, B: 0xee, A: 0xff},
+	Label:        color.RGBA{R: 0xee, G: 0xee, B: 0xee, A: 0xff},
+	Marker:       color.RGBA{R: 0xff, G: 0x8a, B: 0x65, A: 0xff},
+	MarkerText:   color.RGBA{A: 0xff},
+	Portal:       color.RGBA{R: 0xb3, G: 0x9d, B: 0xdb, A: 0xff},
+	Heat:         color.RGBA{R: 0xff, G: 0x70, B: 0x43, A: 0xff},
+
+	FontSizeFrac:          1.0 / 150.0,
+	SpecificLabelSizeFrac: 0.5,
+	PathFrac:              0.002,
+	MarkerFrac:            0.008,
+}
+
+// Themes maps theme names to themes.
+var Themes = map[string]*Theme{
+	"default":      DefaultTheme,
+	"highContrast": HighContrastTheme,
+	"dark":         DarkTheme,
+}
+
+// FontSize gets the size of department labels for a
+// multi-floor rendering of a layout.
+func (t *Theme) FontSize(layout *optishop.Layout) float64 {
+	width, _, _ := MultiFloorGeometry(layout)
+	return width * t.FontSizeFrac
+}
+
+// PathWidth gets the thickness of paths for a multi-floor
+// rendering of a layout.
+func (t *Theme) PathWidth(layout *optishop.Layout) float64 {
+	width, _, _ := MultiFloorGeometry(layout)
+	return width * t.PathFrac
+}
+
+// StartSVG begins an SVG document for the viewport,
+// including an accessible title and a background.
+func (t *Theme) StartSVG(canvas *svg.SVG, v Viewport, title string) {
+	canvas.Start(v.Width, v.Height, v.ViewBox(), `role="img"`,
+		fmt.Sprintf(`aria-label="%s"`, html.EscapeString(title)))
+	canvas.Title(title)
+	canvas.Rect(v.X, v.Y, v.Width, v.Height, `class="background"`, `aria-hidden="true"`,
+		"fill: "+cssColor(t.Background))
+}
+
+// DrawFloor draws all of the objects on a floor.
+func (t *Theme) DrawFloor(canvas *svg.SVG, floor *optishop.Floor, xOff, yOff, fontSize float64) {
+	t.DrawFloorPolygons(canvas, floor, xOff, yOff)
+	t.DrawFloorLabels(canvas, floor, xOff, yOff, fontSize)
+}
+
+// DrawFloorPolygons draws all of the objects on a floor
+// except for the labels.
+func (t *Theme) DrawFloorPolygons(canvas *svg.SVG, floor *optishop.Floor, xOff, yOff float64) {
+	canvas.Group(`aria-hidden="true"`)
+	DrawPolygon(canvas, floor.Bounds, xOff, yOff, `class="floor"`,
+		t.polygonStyle(floor, t.Floor))
+	for _, nonPref := range floor.NonPreferred {
+		if nonPref.Visible {
+			DrawPolygon(canvas, nonPref.Bounds, xOff, yOff, `class="non-preferred"`,
+				"fill: "+cssColor(t.NonPreferred))
+		}
+	}
+	for _, obstacle := range floor.Obstacles {
+		DrawPolygon(canvas, obstacle, xOff, yOff, `class="obstacle"`,
+			t.polygonStyle(floor, t.Obstacle))
+	}
+	canvas.Gend()
+}
+
+// DrawFloorLabels draws all of the zone labels.
+func (t *Theme) DrawFloorLabels(canvas *svg.SVG, floor *optishop.Floor, xOff, yOff,
+	fontSize float64) {
+	t.DrawZoneLabels(canvas, floor.Zones, xOff, yOff, fontSize)
+}
+
+// DrawZoneLabels draws the labels for specified zones.
+func (t *Theme) DrawZoneLabels(canvas *svg.SVG, zones []*optishop.Zone, xOff, yOff,
+	fontSize float64) {
+	for _, zone := range zones {
+		fs := fontSize
+		class := "zone-label"
+		if zone.Specific {
+			fs *= t.SpecificLabelSizeFrac
+			class += " specific"
+		}
+		style := fmt.Sprintf("text-anchor: middle; dominant-baseline: middle; "+
+			"font-size: %.3fpx; fill: %s", fs, cssColor(t.Label))
+		canvas.Text(zone.Location.X+xOff, zone.Location.Y+yOff, zone.Name,
+			`class="`+class+`"`, fmt.Sprintf(`data-zone="%s"`, html.EscapeString(zone.Name)),
+			style)
+	}
+}
+
+// DrawFloors draws every floor of a layout.
+func (t *Theme) DrawFloors(canvas *svg.SVG, layout *optishop.Layout) {
+	t.DrawFloorsViewport(canvas, layout, FullViewport(layout))
+}
+
+// DrawFloorsViewport draws the floors of a layout which
+// are visible in a viewport.
+func (t *Theme) DrawFloorsViewport(canvas *svg.SVG, layout *optishop.Layout, v Viewport) {
+	fontSize := t.FontSize(layout)
+	MultiFloorLoopViewport(layout, v, func(f *optishop.Floor, x, y float64) {
+		canvas.Group(`class="floor-group"`,
+			fmt.Sprintf(`data-floor="%d"`, layout.FloorIndex(f)))
+		t.DrawFloor(canvas, f, x, y, fontSize)
+		canvas.Gend()
+	})
+}
+
+// DrawFloorPath traces out a path on a multi-floor
+// rendering.
+func (t *Theme) DrawFloorPath(canvas *svg.SVG, layout *optishop.Layout, path optishop.FloorPath) {
+	pathWidth := t.PathWidth(layout)
+	pathColor := cssColor(t.Path)
+	for _, part := range path {
+		if len(part.Path) == 0 {
+			continue
+		}
+		x, y := FloorOffset(layout, part.Floor)
+		var pathX, pathY []float64
+		for _, p := range part.Path {
+			pathX = append(pathX, p.X+x)
+			pathY = append(pathY, p.Y+y)
+		}
+		label := fmt.Sprintf("Route on floor %d", part.Floor+1)
+		canvas.Group(`class="route-path"`, `role="img"`,
+			fmt.Sprintf(`aria-label="%s"`, label))
+		canvas.Title(label)
+		canvas.Polyline(pathX, pathY, `class="route-line"`,
+			fmt.Sprintf("stroke-width: %.3fpx; stroke: %s; fill: none", pathWidth, pathColor))
+		canvas.Circle(pathX[len(pathX)-1], pathY[len(pathY)-1], pathWidth*2,
+			`class="route-end"`, "fill: "+pathColor)
+		canvas.Gend()
+	}
+}
+
+func (t *Theme) polygonStyle(floor *optishop.Floor, fill color.RGBA) string {
+	style := "fill: " + cssColor(fill)
+	if t.OutlineFrac != 0 {
+		style += fmt.Sprintf("; stroke: %s; stroke-width: %.3fpx", cssColor(t.Outline),
+			t.outlineWidth(floor))
+	}
+	return style
+}
+
+func (t *Theme) outlineWidth(floor *optishop.Floor) float64 {
+	_, _, w, h := floor.Bounds.Bounds()
+	return math.Max(w, h) * t.OutlineFrac
+}
+
+func cssColor(c color.RGBA) string {
+	if c.A == 0xff {
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("rgba(%d, %d, %d, %.3f)", c.R, c.G, c.B, float64(c.A)/0xff)
+}
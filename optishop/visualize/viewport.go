@@ -0,0 +1,159 @@
+package visualize
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+// ViewportMinSizeFrac is the smallest size of a viewport
+// created by PathViewport, relative to the size of the
+// layout.
+const ViewportMinSizeFrac = 0.25
+
+// A Viewport is a rectangular region of a multi-floor
+// rendering, in the coordinates used by MultiFloorLoop.
+type Viewport struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// FullViewport gets the viewport containing an entire
+// multi-floor rendering.
+func FullViewport(layout *optishop.Layout) Viewport {
+	width, height, _ := MultiFloorGeometry(layout)
+	return Viewport{Width: width, Height: height}
+}
+
+// FloorViewport gets the viewport containing a single
+// floor of a multi-floor rendering, including margins.
+//
+// Vertical margins are halved so that the viewports of
+// neighboring floors do not overlap.
+func FloorViewport(layout *optishop.Layout, floor int) Viewport {
+	width, _, margin := MultiFloorGeometry(layout)
+	_, yOff := FloorOffset(layout, floor)
+	_, y, _, height := layout.Floors[floor].Bounds.Bounds()
+	return Viewport{
+		Y:      y + yOff - margin/2,
+		Width:  width,
+		Height: height + margin,
+	}
+}
+
+// PathViewport gets the viewport containing all of the
+// steps of a path, with a margin around the path so that
+// nearby labels are visible.
+func PathViewport(layout *optishop.Layout, path optishop.FloorPath) Viewport {
+	width, _, margin := MultiFloorGeometry(layout)
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, step := range path {
+		xOff, yOff := FloorOffset(layout, step.Floor)
+		for _, p := range step.Path {
+			minX = math.Min(minX, p.X+xOff)
+			minY = math.Min(minY, p.Y+yOff)
+			maxX = math.Max(maxX, p.X+xOff)
+			maxY = math.Max(maxY, p.Y+yOff)
+		}
+	}
+	if math.IsInf(minX, 1) {
+		return FullViewport(layout)
+	}
+
+	// Avoid zooming in so far that the surrounding area
+	// is no longer recognizable.
+	minSize := width * ViewportMinSizeFrac
+	if maxX-minX < minSize {
+		mid := (minX + maxX) / 2
+		minX, maxX = mid-minSize/2, mid+minSize/2
+	}
+	if maxY-minY < minSize {
+		mid := (minY + maxY) / 2
+		minY, maxY = mid-minSize/2, mid+minSize/2
+	}
+
+	margin /= 2
+	return Viewport{
+		X:      minX - margin,
+		Y:      minY - margin,
+		Width:  maxX - minX + margin*2,
+		Height: maxY - minY + margin*2,
+	}
+}
+
+// FloorOffset gets the offset of a floor in a multi-floor
+// rendering, as passed to the MultiFloorLoop callback.
+func FloorOffset(layout *optishop.Layout, floor int) (x, y float64) {
+	MultiFloorLoop(layout, func(f *optishop.Floor, fx, fy float64) {
+		if f == layout.Floors[floor] {
+			x, y = fx, fy
+		}
+	})
+	return
+}
+
+// RenderedFloorPoint converts a point in a multi-floor
+// rendering to a point on a floor, reversing the offsets
+// from MultiFloorLoop.
+//
+// If floor is negative, the floor is the one whose
+// FloorViewport vertically contains the point, or the
+// closest floor to the point if no viewport contains it.
+func RenderedFloorPoint(layout *optishop.Layout, p optishop.Point,
+	floor int) optishop.FloorPoint {
+	if floor < 0 {
+		floor = 0
+		for i := range layout.Floors {
+			if FloorViewport(layout, i).Y <= p.Y {
+				floor = i
+			}
+		}
+	}
+	x, y := FloorOffset(layout, floor)
+	return optishop.FloorPoint{
+		Floor: floor,
+		Point: optishop.Point{X: p.X - x, Y: p.Y - y},
+	}
+}
+
+// ViewBox formats the viewport as an SVG viewBox
+// attribute.
+func (v Viewport) ViewBox() string {
+	return fmt.Sprintf("viewBox=\"%f %f %f %f\"", v.X, v.Y, v.Width, v.Height)
+}
+
+// Intersects checks if two viewports overlap.
+func (v Viewport) Intersects(v1 Viewport) bool {
+	return v.X < v1.X+v1.Width && v1.X < v.X+v.Width &&
+		v.Y < v1.Y+v1.Height && v1.Y < v.Y+v.Height
+}
+
+// Tile splits the viewport into a 2^zoom by 2^zoom grid of
+// square tiles and returns the tile at the given column
+// and row.
+//
+// Tiles are square so that every zoom level has a
+// consistent scale, meaning that the grid may extend
+// past the bottom or right of the viewport.
+//
+// Returns false if the tile is outside of the grid.
+func (v Viewport) Tile(zoom, col, row int) (Viewport, bool) {
+	if zoom < 0 || zoom > 30 {
+		return Viewport{}, false
+	}
+	numTiles := 1 << uint(zoom)
+	if col < 0 || row < 0 || col >= numTiles || row >= numTiles {
+		return Viewport{}, false
+	}
+	size := math.Max(v.Width, v.Height) / float64(numTiles)
+	return Viewport{
+		X:      v.X + float64(col)*size,
+		Y:      v.Y + float64(row)*size,
+		Width:  size,
+		Height: size,
+	}, true
+}
@@ -76,6 +76,43 @@ func (s *Server) AuthHandler(f http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// AdminHandler is like AuthHandler, but the handler is
+// only called for requests from administrators.
+//
+// In local mode, the only user is an administrator.
+func (s *Server) AdminHandler(f http.HandlerFunc) http.HandlerFunc {
+	return s.AuthHandler(func(w http.ResponseWriter, r *http.Request) {
+		if s.LocalMode {
+			f(w, r)
+			return
+		}
+		user := r.Context().Value(UserKey).(db.UserID)
+		isAdmin, err := s.IsAdmin(user)
+		if err != nil {
+			s.ServeError(w, r, err)
+			return
+		} else if !isAdmin {
+			s.ServeError(w, r, errors.New("not authorized"))
+			return
+		}
+		f(w, r)
+	})
+}
+
+// IsAdmin checks if a user is an administrator.
+func (s *Server) IsAdmin(user db.UserID) (bool, error) {
+	username, err := s.DB.Username(user)
+	if err != nil {
+		return false, err
+	}
+	for _, admin := range s.Admins {
+		if admin == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // OptionalAuthHandler is like AuthHandler, but it always
 // hands requests to f. If the user is not authenticated,
 // then there is simply no UserKey in the context.
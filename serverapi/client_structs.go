@@ -1,12 +1,17 @@
 package serverapi
 
-import "github.com/unixpickle/optishop-server/optishop"
+import (
+	"github.com/unixpickle/optishop-server/optishop"
+	"github.com/unixpickle/optishop-server/optishop/visualize"
+)
 
 type ClientListItem struct {
 	// Fields present only for items the user has added to
 	// their list.
 	ID       string `json:"id,omitempty"`
 	ZoneName string `json:"zone,omitempty"`
+	Cold     bool   `json:"cold,omitempty"`
+	Fragile  bool   `json:"fragile,omitempty"`
 
 	Name        string `json:"name"`
 	PhotoURL    string `json:"photoUrl"`
@@ -41,3 +46,249 @@ type ClientStoreDesc struct {
 
 	Signature string `json:"signature,omitempty"`
 }
+
+type ClientZone struct {
+	ID    int     `json:"id"`
+	Name  string  `json:"name"`
+	Floor int     `json:"floor"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+}
+
+// NewClientZones creates a ClientZone for each zone, where
+// each zone's ID is its index in zones.
+func NewClientZones(l *optishop.Layout, zones []*optishop.Zone) []*ClientZone {
+	res := []*ClientZone{}
+	for i, z := range zones {
+		res = append(res, &ClientZone{
+			ID:    i,
+			Name:  z.Name,
+			Floor: l.ZoneFloor(z),
+			X:     z.Location.X,
+			Y:     z.Location.Y,
+		})
+	}
+	return res
+}
+
+type ClientDirection struct {
+	Floor int     `json:"floor"`
+	X     float64 `json:"x"`
+	Y     float64 `json:"y"`
+	Text  string  `json:"text"`
+}
+
+type ClientPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// A ClientPathStep is the client representation of an
+// optishop.FloorPathStep.
+//
+// Portal fields are only set if the step ends by going
+// through a portal.
+type ClientPathStep struct {
+	Floor        int            `json:"floor"`
+	Points       []*ClientPoint `json:"points"`
+	Length       float64        `json:"length"`
+	SourcePortal *int           `json:"sourcePortal,omitempty"`
+	DestPortal   *int           `json:"destPortal,omitempty"`
+	PortalType   string         `json:"portalType,omitempty"`
+}
+
+// NewClientPathSteps creates a ClientPathStep for each
+// step of a path.
+func NewClientPathSteps(l *optishop.Layout, path optishop.FloorPath) []*ClientPathStep {
+	res := []*ClientPathStep{}
+	for i, step := range path {
+		clientStep := &ClientPathStep{
+			Floor:  step.Floor,
+			Points: []*ClientPoint{},
+			Length: step.Path.Length(),
+		}
+		for _, p := range step.Path {
+			clientStep.Points = append(clientStep.Points, &ClientPoint{X: p.X, Y: p.Y})
+		}
+		if i+1 < len(path) {
+			source, dest := step.SourcePortal, step.DestPortal
+			clientStep.SourcePortal = &source
+			clientStep.DestPortal = &dest
+			if portal := l.Portal(source); portal != nil {
+				clientStep.PortalType = string(portal.Type)
+			}
+		}
+		res = append(res, clientStep)
+	}
+	return res
+}
+
+// A ClientLeg is one leg of a route, leading to an item on
+// the user's list, or to the end of the route if Item is
+// nil.
+//
+// Lengths are measured in layout units, while the
+// estimate is measured in meters and seconds.
+type ClientLeg struct {
+	Item       *ClientListItem    `json:"item,omitempty"`
+	From       string             `json:"from"`
+	To         string             `json:"to"`
+	Length     float64            `json:"length"`
+	Steps      []*ClientPathStep  `json:"steps"`
+	Directions []*ClientDirection `json:"directions"`
+	Estimate   *ClientEstimate    `json:"estimate"`
+}
+
+// NewClientLegs creates a ClientLeg for each path in a
+// route, where the i-th path leads to the i-th item and
+// has the i-th estimate.
+func NewClientLegs(l *optishop.Layout, paths []optishop.FloorPath,
+	estimates []optishop.TravelEstimate, items []*ClientListItem) []*ClientLeg {
+	res := []*ClientLeg{}
+	for i, path := range paths {
+		leg := &ClientLeg{
+			Steps:      NewClientPathSteps(l, path),
+			Directions: []*ClientDirection{},
+			Estimate:   NewClientEstimate(estimates[i]),
+		}
+		for _, step := range leg.Steps {
+			leg.Length += step.Length
+		}
+		if first, last := path[0], path[len(path)-1]; len(first.Path) > 0 && len(last.Path) > 0 {
+			leg.From = nearestZoneName(l, first.Floor, first.Path[0])
+			leg.To = nearestZoneName(l, last.Floor, last.Path[len(last.Path)-1])
+		}
+		for _, d := range optishop.Directions(l, path) {
+			leg.Directions = append(leg.Directions, &ClientDirection{
+				Floor: d.Floor,
+				X:     d.Location.X,
+				Y:     d.Location.Y,
+				Text:  d.Text,
+			})
+		}
+		if i < len(items) {
+			leg.Item = items[i]
+		}
+		res = append(res, leg)
+	}
+	return res
+}
+
+func nearestZoneName(l *optishop.Layout, floor int, p optishop.Point) string {
+	if z := l.Floors[floor].NearestZone(p); z != nil {
+		return z.Name
+	}
+	return ""
+}
+
+// A ClientEstimate is a travel estimate, where distances
+// are in meters and times are in seconds.
+type ClientEstimate struct {
+	Distance float64 `json:"distance"`
+	Time     float64 `json:"time"`
+}
+
+func NewClientEstimate(e optishop.TravelEstimate) *ClientEstimate {
+	return &ClientEstimate{Distance: e.Distance, Time: e.Time.Seconds()}
+}
+
+// A ClientHitTest describes what is at a point on a map.
+//
+// The point is relative to the floor, while the distances
+// to zones are measured in layout units.
+type ClientHitTest struct {
+	Floor      int              `json:"floor"`
+	Point      *ClientPoint     `json:"point"`
+	InFloor    bool             `json:"inFloor"`
+	InObstacle bool             `json:"inObstacle"`
+	Zones      []*ClientHitZone `json:"zones"`
+}
+
+// A ClientHitZone is a zone near the point of a hit test.
+type ClientHitZone struct {
+	Name     string       `json:"name"`
+	Specific bool         `json:"specific"`
+	Location *ClientPoint `json:"location"`
+	Distance float64      `json:"distance"`
+}
+
+// NewClientHitTest finds the zones near a point and checks
+// whether the point is inside of an obstacle.
+func NewClientHitTest(l *optishop.Layout, p optishop.FloorPoint, numZones int) *ClientHitTest {
+	floor := l.Floors[p.Floor]
+	res := &ClientHitTest{
+		Floor:      p.Floor,
+		Point:      &ClientPoint{X: p.X, Y: p.Y},
+		InFloor:    floor.Contains(p.Point),
+		InObstacle: floor.InObstacle(p.Point),
+		Zones:      []*ClientHitZone{},
+	}
+	for _, z := range floor.NearestZones(p.Point, numZones) {
+		res.Zones = append(res.Zones, &ClientHitZone{
+			Name:     z.Name,
+			Specific: z.Specific,
+			Location: &ClientPoint{X: z.Location.X, Y: z.Location.Y},
+			Distance: z.Location.Distance(p.Point),
+		})
+	}
+	return res
+}
+
+// A ClientZoneFrequency records how many list entries
+// refer to a zone.
+type ClientZoneFrequency struct {
+	Floor int    `json:"floor"`
+	Zone  string `json:"zone"`
+	Count int    `json:"count"`
+}
+
+func NewClientZoneFrequencies(freqs []*visualize.ZoneFrequency) []*ClientZoneFrequency {
+	res := []*ClientZoneFrequency{}
+	for _, freq := range freqs {
+		res = append(res, &ClientZoneFrequency{
+			Floor: freq.Floor,
+			Zone:  freq.Zone.Name,
+			Count: freq.Count,
+		})
+	}
+	return res
+}
+
+// A ClientLayoutChange is a difference between the
+// original and the corrected layout of a store.
+type ClientLayoutChange struct {
+	Floor    int          `json:"floor"`
+	Location *ClientPoint `json:"location,omitempty"`
+	Message  string       `json:"message"`
+}
+
+func NewClientLayoutChanges(changes []*optishop.LayoutChange) []*ClientLayoutChange {
+	res := []*ClientLayoutChange{}
+	for _, change := range changes {
+		c := &ClientLayoutChange{Floor: change.Floor, Message: change.Message}
+		if change.Location != nil {
+			c.Location = &ClientPoint{X: change.Location.X, Y: change.Location.Y}
+		}
+		res = append(res, c)
+	}
+	return res
+}
+
+// A ClientLayoutEdits lists the corrections to a store's
+// layout, along with a description of each.
+type ClientLayoutEdits struct {
+	Edits        []*optishop.LayoutEdit `json:"edits"`
+	Descriptions []string               `json:"descriptions"`
+}
+
+func NewClientLayoutEdits(edits []*optishop.LayoutEdit) *ClientLayoutEdits {
+	res := &ClientLayoutEdits{
+		Edits:        []*optishop.LayoutEdit{},
+		Descriptions: []string{},
+	}
+	for _, edit := range edits {
+		res.Edits = append(res.Edits, edit)
+		res.Descriptions = append(res.Descriptions, edit.String())
+	}
+	return res
+}
@@ -0,0 +1,142 @@
+package serverapi
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+// RouteEndpoints specifies where a route begins and ends.
+type RouteEndpoints struct {
+	Start optishop.FloorPoint
+	End   optishop.FloorPoint
+}
+
+// DefaultRouteEndpoints creates endpoints which go from
+// the layout's entrance to its checkout.
+func DefaultRouteEndpoints(l *optishop.Layout) (*RouteEndpoints, error) {
+	entrance, checkout := EntranceAndCheckout(l)
+	if entrance == nil || checkout == nil {
+		return nil, errors.New("route endpoints: unable to locate entrance or checkout")
+	}
+	points := ZonesToPoints(l, []*optishop.Zone{entrance, checkout})
+	return &RouteEndpoints{Start: points[0], End: points[1]}, nil
+}
+
+// ParseRouteEndpoints determines the endpoints of a route
+// from the parameters of a request.
+//
+// The "entrance" and "checkout" parameters select zones
+// by their index in Entrances() and Checkouts().
+// The "endZone" parameter names any zone to end at instead
+// of a checkout, and "roundTrip=true" ends the route back
+// at the entrance.
+// The "startZone" parameter names a zone to start at, or
+// "startFloor", "startX", and "startY" specify a point to
+// start at, e.g. the shopper's current location.
+//
+// By default, the route goes between the zones found by
+// EntranceAndCheckout.
+func ParseRouteEndpoints(l *optishop.Layout, r *http.Request) (*RouteEndpoints, error) {
+	entrance, checkout := EntranceAndCheckout(l)
+
+	if idx := r.FormValue("entrance"); idx != "" {
+		entrance = zoneAtIndex(Entrances(l), idx)
+		if entrance == nil {
+			return nil, errors.New("route endpoints: invalid entrance")
+		}
+	}
+	if idx := r.FormValue("checkout"); idx != "" {
+		checkout = zoneAtIndex(Checkouts(l), idx)
+		if checkout == nil {
+			return nil, errors.New("route endpoints: invalid checkout")
+		}
+	}
+	if name := r.FormValue("endZone"); name != "" {
+		checkout = l.Zone(name)
+		if checkout == nil {
+			return nil, errors.New("route endpoints: invalid end zone")
+		}
+	}
+	if entrance == nil || checkout == nil {
+		return nil, errors.New("route endpoints: unable to locate entrance or checkout")
+	}
+
+	points := ZonesToPoints(l, []*optishop.Zone{entrance, checkout})
+	res := &RouteEndpoints{Start: points[0], End: points[1]}
+	if r.FormValue("roundTrip") == "true" {
+		res.End = res.Start
+	}
+
+	if name := r.FormValue("startZone"); name != "" {
+		zone := l.Zone(name)
+		if zone == nil {
+			return nil, errors.New("route endpoints: invalid start zone")
+		}
+		res.Start = ZonesToPoints(l, []*optishop.Zone{zone})[0]
+	} else if r.FormValue("startFloor") != "" {
+		point, err := parseFloorPoint(l, r.FormValue("startFloor"), r.FormValue("startX"),
+			r.FormValue("startY"))
+		if err != nil {
+			return nil, errors.Wrap(err, "route endpoints")
+		}
+		res.Start = point
+	}
+
+	return res, nil
+}
+
+// Entrances finds all of the entrance zones in a layout.
+func Entrances(l *optishop.Layout) []*optishop.Zone {
+	var res []*optishop.Zone
+	for _, f := range l.Floors {
+		for _, z := range f.Zones {
+			if z.Entrance {
+				res = append(res, z)
+			}
+		}
+	}
+	return res
+}
+
+// Checkouts finds all of the checkout zones in a layout.
+func Checkouts(l *optishop.Layout) []*optishop.Zone {
+	var res []*optishop.Zone
+	for _, f := range l.Floors {
+		for _, z := range f.Zones {
+			if z.Checkout {
+				res = append(res, z)
+			}
+		}
+	}
+	return res
+}
+
+func zoneAtIndex(zones []*optishop.Zone, idxStr string) *optishop.Zone {
+	idx, err := strconv.Atoi(idxStr)
+	if err != nil || idx < 0 || idx >= len(zones) {
+		return nil
+	}
+	return zones[idx]
+}
+
+func parseFloorPoint(l *optishop.Layout, floorStr, xStr, yStr string) (optishop.FloorPoint, error) {
+	floor, err := strconv.Atoi(floorStr)
+	if err != nil || floor < 0 || floor >= len(l.Floors) {
+		return optishop.FloorPoint{}, &BadRequestError{Message: "invalid floor"}
+	}
+	x, err := ParseFinite(xStr)
+	if err != nil {
+		return optishop.FloorPoint{}, &BadRequestError{Message: "invalid x coordinate"}
+	}
+	y, err := ParseFinite(yStr)
+	if err != nil {
+		return optishop.FloorPoint{}, &BadRequestError{Message: "invalid y coordinate"}
+	}
+	return optishop.FloorPoint{
+		Point: optishop.Point{X: x, Y: y},
+		Floor: floor,
+	}, nil
+}
@@ -0,0 +1,23 @@
+package serverapi
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestParseFloorPointNonFinite(t *testing.T) {
+	layout := testingViewportLayout()
+	for _, coords := range [][2]string{{"NaN", "1"}, {"1", "Inf"}, {"-Inf", "1"}, {"nan", "1"}} {
+		_, err := parseFloorPoint(layout, "0", coords[0], coords[1])
+		if _, ok := errors.Cause(err).(*BadRequestError); !ok {
+			t.Errorf("coordinates %v: expected bad request but got %v", coords, err)
+		}
+	}
+	point, err := parseFloorPoint(layout, "0", "1.5", "2")
+	if err != nil {
+		t.Fatal(err)
+	} else if point.X != 1.5 || point.Y != 2 || point.Floor != 0 {
+		t.Errorf("unexpected point: %v", point)
+	}
+}
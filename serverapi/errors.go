@@ -2,6 +2,7 @@ package serverapi
 
 import (
 	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -20,14 +21,30 @@ var errorMap = map[string]string{
 	"not authenticated":                                                "You are no longer signed in. Please refresh the page and sign in.",
 	"get store: store not found":                                       "The store could not be found. Did you delete it?",
 	"remove list entry: entry not found":                               "The entry does not exist. Did you delete it?",
+	"modify list entry: entry not found":                               "The entry does not exist. Did you delete it?",
+	"check off list entry: entry not found":                            "The entry does not exist. Did you delete it?",
 	"the specified location does not exist":                            "The specified location does not exist.",
+	"sort entries: unable to connect all points":                       "Some items on your list cannot be reached with your routing preferences.",
+	"route paths: unable to connect two points":                        "Some items on your list cannot be reached with your routing preferences.",
+	"new raster canvas: image is too large":                            "The requested map area is too large to render as an image.",
 }
 
 var errorRegexes = map[*regexp.Regexp]string{
 	regexp.MustCompile("^locate product: aisle (.*) is missing from the map$"):    "The product is located at aisle $1, but $1 is missing from the map.",
+	regexp.MustCompile("^.*store source .* is unavailable: .*$"):                  "This store's information is temporarily unavailable. Please try again later.",
 	regexp.MustCompile("^sort entries: invalid zone \"(.*)\" for list entry .*$"): "Your list includes a product at aisle $1, but $1 is missing from the map. Try removing the product and re-adding it.",
 }
 
+// A BadRequestError indicates that the parameters of a
+// request are invalid, and is served with a 400 status.
+type BadRequestError struct {
+	Message string
+}
+
+func (b *BadRequestError) Error() string {
+	return b.Message
+}
+
 // HumanizeError turns an error message into a more
 // user-friendly message.
 func HumanizeError(err error) error {
@@ -62,9 +79,15 @@ func (s *Server) ServeError(w http.ResponseWriter, r *http.Request, err error) {
 	message := HumanizeError(err).Error()
 	LogRequest(r, "serving error: %s", message)
 
+	status := http.StatusOK
+	if _, ok := errors.Cause(err).(*BadRequestError); ok {
+		status = http.StatusBadRequest
+	}
+
 	if IsAPIRequest(r) {
-		obj := map[string]string{"error": message}
-		ServeObject(w, r, obj)
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
 		return
 	}
 
@@ -75,5 +98,6 @@ func (s *Server) ServeError(w http.ResponseWriter, r *http.Request, err error) {
 	}
 
 	pageData = bytes.Replace(pageData, []byte("INSERT_ERROR_HERE"), []byte(message), 1)
+	w.WriteHeader(status)
 	w.Write(pageData)
 }
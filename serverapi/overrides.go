@@ -0,0 +1,114 @@
+package serverapi
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+// A LayoutOverride is a list of corrections to the layout
+// of a store, which are applied on top of the layout from
+// the store's source.
+type LayoutOverride struct {
+	SourceName   string
+	StoreName    string
+	StoreAddress string
+
+	Edits    []*optishop.LayoutEdit
+	Modified time.Time
+}
+
+// Apply applies the override's edits to a copy of the
+// layout.
+func (l *LayoutOverride) Apply(layout *optishop.Layout) (*optishop.Layout, error) {
+	res, err := optishop.ApplyLayoutEdits(layout, l.Edits)
+	if err != nil {
+		return nil, errors.Wrap(err, "apply layout override")
+	}
+	return res, nil
+}
+
+// LayoutOverrides persists LayoutOverrides in a directory,
+// using one file per store.
+type LayoutOverrides struct {
+	Dir string
+
+	lock sync.RWMutex
+}
+
+// NewLayoutOverrides creates a LayoutOverrides, creating
+// the directory if it does not exist.
+func NewLayoutOverrides(dir string) (*LayoutOverrides, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "create layout overrides")
+	}
+	return &LayoutOverrides{Dir: dir}, nil
+}
+
+// Get reads the override for a store.
+//
+// Returns nil if the store has no override.
+func (l *LayoutOverrides) Get(sourceName, storeName, storeAddress string) (*LayoutOverride,
+	error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	data, err := ioutil.ReadFile(l.path(sourceName, storeName, storeAddress))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "get layout override")
+	}
+	var res LayoutOverride
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, errors.Wrap(err, "get layout override")
+	}
+	return &res, nil
+}
+
+// Put saves the override for a store, replacing any
+// existing override.
+//
+// If the override has no edits, the existing override is
+// deleted.
+func (l *LayoutOverrides) Put(o *LayoutOverride) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	path := l.path(o.SourceName, o.StoreName, o.StoreAddress)
+	if len(o.Edits) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "put layout override")
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(o)
+	if err != nil {
+		return errors.Wrap(err, "put layout override")
+	}
+	// Write to a temporary file so that a failed write does
+	// not destroy the previous override.
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return errors.Wrap(err, "put layout override")
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return errors.Wrap(err, "put layout override")
+	}
+	return nil
+}
+
+func (l *LayoutOverrides) path(sourceName, storeName, storeAddress string) string {
+	key, _ := json.Marshal([]string{sourceName, storeName, storeAddress})
+	hash := sha256.Sum256(key)
+	return filepath.Join(l.Dir, base64.URLEncoding.EncodeToString(hash[:])+".json")
+}
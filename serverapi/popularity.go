@@ -0,0 +1,143 @@
+package serverapi
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/unixpickle/optishop-server/optishop/db"
+	"github.com/unixpickle/optishop-server/optishop/visualize"
+)
+
+// SameStore checks if two store records from (possibly
+// different) users refer to the same physical store.
+func SameStore(info1, info2 *db.StoreInfo) bool {
+	return info1.SourceName == info2.SourceName && info1.StoreName == info2.StoreName &&
+		info1.StoreAddress == info2.StoreAddress
+}
+
+// PopularityTTL is how long a PopularityCache reuses the
+// popularity of a store's zones.
+const PopularityTTL = time.Minute * 10
+
+// A PopularityCache caches the results of ZonePopularity,
+// which reads the lists of every user.
+type PopularityCache struct {
+	DB db.DB
+
+	lock        sync.Mutex
+	cache       map[cacheKey][]*visualize.ZoneFrequency
+	expirations map[cacheKey]time.Time
+}
+
+// NewPopularityCache creates a PopularityCache for the
+// database.
+func NewPopularityCache(d db.DB) *PopularityCache {
+	return &PopularityCache{
+		DB:          d,
+		cache:       map[cacheKey][]*visualize.ZoneFrequency{},
+		expirations: map[cacheKey]time.Time{},
+	}
+}
+
+// ZonePopularity is like the ZonePopularity function, but
+// only scans the database if the store's cached result is
+// older than PopularityTTL.
+//
+// Scans are serialized, so concurrent requests do not scan
+// the database more than once.
+func (p *PopularityCache) ZonePopularity(store *db.StoreInfo) ([]*visualize.ZoneFrequency,
+	error) {
+	key := cacheKey{Source: store.SourceName, Name: store.StoreName,
+		Address: store.StoreAddress}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+	for k, expiration := range p.expirations {
+		if now.After(expiration) {
+			delete(p.cache, k)
+			delete(p.expirations, k)
+		}
+	}
+	if res, ok := p.cache[key]; ok {
+		return res, nil
+	}
+
+	res, err := ZonePopularity(p.DB, store)
+	if err != nil {
+		return nil, err
+	}
+	p.cache[key] = res
+	p.expirations[key] = now.Add(PopularityTTL)
+	return res, nil
+}
+
+// ZonePopularity counts how many list entries refer to
+// each zone of a store, across the lists and the checked
+// off entries of every user.
+//
+// The result is sorted from most to least popular.
+func ZonePopularity(d db.DB, store *db.StoreInfo) ([]*visualize.ZoneFrequency, error) {
+	users, err := d.Users()
+	if err != nil {
+		return nil, errors.Wrap(err, "zone popularity")
+	}
+
+	type zoneKey struct {
+		Floor int
+		Name  string
+	}
+	counts := map[zoneKey]*visualize.ZoneFrequency{}
+	var res []*visualize.ZoneFrequency
+	addEntry := func(info *db.ListEntryInfo) {
+		if info.Zone == nil {
+			return
+		}
+		key := zoneKey{Floor: info.Floor, Name: info.Zone.Name}
+		if freq, ok := counts[key]; ok {
+			freq.Count++
+		} else {
+			freq = &visualize.ZoneFrequency{
+				Floor: info.Floor,
+				Zone:  info.Zone,
+				Count: 1,
+			}
+			counts[key] = freq
+			res = append(res, freq)
+		}
+	}
+
+	for _, user := range users {
+		records, err := d.Stores(user)
+		if err != nil {
+			return nil, errors.Wrap(err, "zone popularity")
+		}
+		for _, record := range records {
+			if !SameStore(record.Info, store) {
+				continue
+			}
+			entries, err := d.ListEntries(user, record.ID)
+			if err != nil {
+				return nil, errors.Wrap(err, "zone popularity")
+			}
+			for _, entry := range entries {
+				addEntry(entry.Info)
+			}
+			history, err := d.ListHistory(user, record.ID)
+			if err != nil {
+				return nil, errors.Wrap(err, "zone popularity")
+			}
+			for _, entry := range history {
+				addEntry(entry.Info)
+			}
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Count > res[j].Count
+	})
+	return res, nil
+}
@@ -0,0 +1,110 @@
+package serverapi
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/unixpickle/optishop-server/optishop"
+	"github.com/unixpickle/optishop-server/optishop/db"
+	"github.com/unixpickle/optishop-server/optishop/visualize"
+)
+
+// RoutingProfileKey is the user metadata field which
+// stores the name of the user's routing profile.
+const RoutingProfileKey = "routingProfile"
+
+// Names of routing profiles that a user may choose.
+const (
+	StandardProfile   = "standard"
+	AccessibleProfile = "accessible"
+)
+
+// AccessibleClearanceFrac controls how much clearance the
+// accessible routing profile requires, with respect to
+// the size of the layout.
+const AccessibleClearanceFrac = 0.004
+
+// MapThemeKey is the user metadata field which stores the
+// name of the user's map theme, which is a key in
+// visualize.Themes.
+const MapThemeKey = "mapTheme"
+
+// DefaultMapTheme is the name of the theme used when the
+// user has not chosen one.
+const DefaultMapTheme = "default"
+
+// RoutingProfileName gets the name of the user's routing
+// profile, defaulting to StandardProfile.
+func (s *Server) RoutingProfileName(user db.UserID) (string, error) {
+	name, err := s.DB.UserMetadata(user, RoutingProfileKey)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return StandardProfile, nil
+		}
+		return "", errors.Wrap(err, "get routing profile")
+	}
+	return name, nil
+}
+
+// FloorConnector creates a FloorConnector for the layout
+// which honors the user's routing profile.
+func (s *Server) FloorConnector(user db.UserID,
+	layout *optishop.Layout) (*optishop.FloorConnector, error) {
+	name, err := s.RoutingProfileName(user)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := RoutingProfile(name, layout)
+	if err != nil {
+		return nil, err
+	}
+	return optishop.NewFloorConnectorProfile(layout, profile), nil
+}
+
+// RoutingProfile creates the routing profile with the
+// given name for a layout.
+//
+// The standard profile is represented as nil.
+func RoutingProfile(name string, layout *optishop.Layout) (*optishop.RoutingProfile, error) {
+	switch name {
+	case StandardProfile:
+		return nil, nil
+	case AccessibleProfile:
+		size := optishop.LayoutSize(layout)
+		return optishop.AccessibleProfile(size * AccessibleClearanceFrac), nil
+	default:
+		return nil, errors.New("unknown routing profile: " + name)
+	}
+}
+
+// MapThemeName gets the name of the user's map theme,
+// defaulting to DefaultMapTheme.
+func (s *Server) MapThemeName(user db.UserID) (string, error) {
+	name, err := s.DB.UserMetadata(user, MapThemeKey)
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return DefaultMapTheme, nil
+		}
+		return "", errors.Wrap(err, "get map theme")
+	}
+	return name, nil
+}
+
+// UserMapTheme gets the theme that the user has chosen for
+// rendering maps.
+func (s *Server) UserMapTheme(user db.UserID) (*visualize.Theme, error) {
+	name, err := s.MapThemeName(user)
+	if err != nil {
+		return nil, err
+	}
+	return MapTheme(name)
+}
+
+// MapTheme looks up a map theme by name.
+func MapTheme(name string) (*visualize.Theme, error) {
+	theme, ok := visualize.Themes[name]
+	if !ok {
+		return nil, errors.New("unknown map theme: " + name)
+	}
+	return theme, nil
+}
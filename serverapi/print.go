@@ -0,0 +1,127 @@
+package serverapi
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/unixpickle/optishop-server/optishop"
+	"github.com/unixpickle/optishop-server/optishop/db"
+	"github.com/unixpickle/optishop-server/optishop/visualize"
+)
+
+// A PrintItem is a row of a printable shopping list.
+type PrintItem struct {
+	Name     string
+	Zone     string
+	Price    string
+	Quantity int
+}
+
+// PrintItems creates the rows of a printable shopping
+// list, combining identical items into a single row with
+// a quantity.
+//
+// Rows are ordered by the first occurrence of each item.
+func PrintItems(items []*ClientListItem) []*PrintItem {
+	type itemKey struct {
+		Name string
+		Zone string
+	}
+	var res []*PrintItem
+	rows := map[itemKey]*PrintItem{}
+	for _, item := range items {
+		key := itemKey{Name: item.Name, Zone: item.ZoneName}
+		if row, ok := rows[key]; ok {
+			row.Quantity++
+			continue
+		}
+		row := &PrintItem{
+			Name:     item.Name,
+			Zone:     item.ZoneName,
+			Price:    item.Price,
+			Quantity: 1,
+		}
+		rows[key] = row
+		res = append(res, row)
+	}
+	return res
+}
+
+// PrintableList renders a print-optimized HTML page with
+// a shopping list followed by a map of the route on each
+// floor that the route visits.
+//
+// The items should correspond to the sorted entries.
+func PrintableList(title string, layout *optishop.Layout, paths []optishop.FloorPath,
+	sorted []*db.ListEntry, items []*ClientListItem, theme *visualize.Theme) ([]byte, error) {
+	visited := make([]bool, len(layout.Floors))
+	for _, path := range paths {
+		for _, step := range path {
+			visited[step.Floor] = true
+		}
+	}
+	var maps []template.HTML
+	for floor, ok := range visited {
+		if ok {
+			viewport := visualize.FloorViewport(layout, floor)
+			data := RouteMapSVG(layout, paths, sorted, items, viewport, theme)
+			maps = append(maps, template.HTML(data))
+		}
+	}
+
+	var buf bytes.Buffer
+	err := printTemplate.Execute(&buf, map[string]interface{}{
+		"Title": title,
+		"Items": PrintItems(items),
+		"Maps":  maps,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var printTemplate = template.Must(template.New("print").Parse(`<!doctype html>
+<html>
+    <head>
+        <title>{{.Title}}</title>
+        <meta charset="utf-8">
+        <style>
+            @page { margin: 1.5cm; }
+            body { font-family: sans-serif; margin: 0; color: black; }
+            h1 { font-size: 20pt; }
+            table { width: 100%; border-collapse: collapse; }
+            th, td { text-align: left; padding: 6pt; border-bottom: 1px solid #999; }
+            tr { page-break-inside: avoid; }
+            .check { width: 14pt; }
+            .check::before { content: ''; display: inline-block; width: 10pt; height: 10pt; border: 1px solid black; }
+            .quantity, .price { text-align: right; white-space: nowrap; }
+            .map { page-break-before: always; }
+            .map svg { width: 100%; max-height: 24cm; }
+            @media screen { body { margin: 1.5cm; } }
+        </style>
+    </head>
+    <body>
+        <h1>{{.Title}}</h1>
+        <table>
+            <thead>
+                <tr><th></th><th>Item</th><th>Aisle</th><th class="quantity">Qty</th><th class="price">Price</th></tr>
+            </thead>
+            <tbody>
+                {{range .Items}}
+                <tr>
+                    <td class="check"></td>
+                    <td>{{.Name}}</td>
+                    <td>{{.Zone}}</td>
+                    <td class="quantity">{{.Quantity}}</td>
+                    <td class="price">{{.Price}}</td>
+                </tr>
+                {{end}}
+            </tbody>
+        </table>
+        {{range .Maps}}
+        <div class="map">{{.}}</div>
+        {{end}}
+    </body>
+</html>
+`))
@@ -1,21 +1,102 @@
 package serverapi
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/unixpickle/optishop-server/optishop"
 	"github.com/unixpickle/optishop-server/optishop/db"
 )
 
-// SortEntries finds the optimal route and returns the
-// list entries sorted by this route.
+// RouteContext creates a context for planning a route
+// during a request.
+//
+// The context is done when the request is cancelled or
+// when the server's RouteTimeout elapses, at which point
+// route planning uses the best route it has found.
+// If the distances between stops have not all been
+// computed by then, planning fails instead.
+func (s *Server) RouteContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if s.RouteTimeout <= 0 {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), s.RouteTimeout)
+}
+
+// Precedence groups for list entries.
+// Entries in later groups are picked up later in a trip.
+const (
+	RegularGroup = iota
+	FragileGroup
+	ColdGroup
+)
+
+// ColdDepartments lists substrings of department names
+// whose products should be treated as cold, even if the
+// user has not marked them as such.
+var ColdDepartments = []string{"frozen", "refrigerated", "dairy"}
+
+// EntryGroup determines the precedence group for a list
+// entry based on its flags and its department.
+func EntryGroup(info *db.ListEntryInfo) int {
+	if info.Cold {
+		return ColdGroup
+	}
+	if info.Zone != nil && !info.Zone.Specific {
+		name := strings.ToLower(info.Zone.Name)
+		for _, dept := range ColdDepartments {
+			if strings.Contains(name, dept) {
+				return ColdGroup
+			}
+		}
+	}
+	if info.Fragile {
+		return FragileGroup
+	}
+	return RegularGroup
+}
+
+// RemainingEntries filters a list to the entries which
+// the shopper has yet to pick up, as specified by the
+// comma-separated "remaining" request parameter.
+//
+// If the parameter is not specified, all entries are
+// remaining.
+func RemainingEntries(list []*db.ListEntry, r *http.Request) []*db.ListEntry {
+	r.ParseForm()
+	values, ok := r.Form["remaining"]
+	if !ok {
+		return list
+	}
+	ids := map[db.ListEntryID]bool{}
+	for _, value := range values {
+		for _, id := range strings.Split(value, ",") {
+			ids[db.ListEntryID(id)] = true
+		}
+	}
+	var res []*db.ListEntry
+	for _, entry := range list {
+		if ids[entry.ID] {
+			res = append(res, entry)
+		}
+	}
+	return res
+}
+
+// SortEntries finds the optimal route between the
+// endpoints and returns the list entries sorted by this
+// route.
 //
 // The entries are copied and their zones are replaced
 // with actual pointers from store.Layout().
-func SortEntries(list []*db.ListEntry, store optishop.Store,
-	conn *optishop.FloorConnector) ([]*db.ListEntry, error) {
-	layout := store.Layout()
+//
+// If ctx is done before the optimal route is found, the
+// best route found so far is used.
+func SortEntries(ctx context.Context, list []*db.ListEntry, layout *optishop.Layout,
+	conn *optishop.FloorConnector, endpoints *RouteEndpoints) ([]*db.ListEntry, error) {
 	newList := make([]*db.ListEntry, len(list))
 	for i, entry := range list {
 		info := *entry.Info
@@ -30,30 +111,48 @@ func SortEntries(list []*db.ListEntry, store optishop.Store,
 		}
 	}
 
-	entrance, checkout := EntranceAndCheckout(layout)
-	if entrance == nil || checkout == nil {
-		return nil, errors.New("sort entries: unable to locate entrance or checkout")
+	// Each stop is a zone and a precedence group, since
+	// items in the same zone may be picked up at different
+	// points in the trip.
+	type stop struct {
+		Zone  *optishop.Zone
+		Group int
 	}
-
-	zones := make([]*optishop.Zone, 0, len(list)+2)
-	zones = append(zones, entrance)
-	zoneSet := map[*optishop.Zone]bool{}
+	stops := make([]stop, 0, len(list))
+	stopSet := map[stop]bool{}
 	for _, entry := range newList {
-		if !zoneSet[entry.Info.Zone] {
-			zoneSet[entry.Info.Zone] = true
-			zones = append(zones, entry.Info.Zone)
+		s := stop{Zone: entry.Info.Zone, Group: EntryGroup(entry.Info)}
+		if !stopSet[s] {
+			stopSet[s] = true
+			stops = append(stops, s)
 		}
 	}
-	zones = append(zones, checkout)
-	points := ZonesToPoints(layout, zones)
-	distFunc := conn.DistanceFunc(points)
-	solution := optishop.SolveTSP(len(points), distFunc)
+
+	zones := make([]*optishop.Zone, len(stops))
+	groups := make([]int, len(stops)+2)
+	for i, s := range stops {
+		zones[i] = s.Zone
+		groups[i+1] = s.Group
+	}
+	points := make([]optishop.FloorPoint, 0, len(stops)+2)
+	points = append(points, endpoints.Start)
+	points = append(points, ZonesToPoints(layout, zones)...)
+	points = append(points, endpoints.End)
+
+	distFunc, err := conn.DistanceFuncContext(ctx, points)
+	if err != nil {
+		return nil, errors.Wrap(err, "sort entries")
+	} else if distFunc == nil {
+		return nil, errors.New("sort entries: unable to connect all points")
+	}
+	solver := optishop.GroupedTSPSolver{Groups: groups}
+	solution := solver.SolveTSPContext(ctx, len(points), distFunc)
 
 	var result []*db.ListEntry
 	for _, idx := range solution[1 : len(solution)-1] {
-		zone := zones[idx]
+		s := stops[idx-1]
 		for _, x := range newList {
-			if x.Info.Zone == zone {
+			if x.Info.Zone == s.Zone && EntryGroup(x.Info) == s.Group {
 				result = append(result, x)
 			}
 		}
@@ -62,31 +161,31 @@ func SortEntries(list []*db.ListEntry, store optishop.Store,
 	return result, nil
 }
 
-// RoutePaths finds the optimal route and returns all of
-// the path segments of it, as well as the sorted list of
-// entries for convenience.
-func RoutePaths(list []*db.ListEntry, store optishop.Store,
-	conn *optishop.FloorConnector) ([]optishop.FloorPath, []*db.ListEntry, error) {
-	entrance, checkout := EntranceAndCheckout(store.Layout())
-	if entrance == nil || checkout == nil {
-		return nil, nil, errors.New("route paths: unable to locate entrance or checkout")
-	}
-
-	sorted, err := SortEntries(list, store, conn)
+// RoutePaths finds the optimal route between the
+// endpoints and returns all of the path segments of it,
+// as well as the sorted list of entries for convenience.
+//
+// The context only limits sorting, so the legs of the best
+// route found are computed even if ctx is done.
+func RoutePaths(ctx context.Context, list []*db.ListEntry, layout *optishop.Layout,
+	conn *optishop.FloorConnector,
+	endpoints *RouteEndpoints) ([]optishop.FloorPath, []*db.ListEntry, error) {
+	sorted, err := SortEntries(ctx, list, layout, conn, endpoints)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "route paths")
 	}
 
-	zones := make([]*optishop.Zone, len(sorted)+2)
-	zones[0] = entrance
+	zones := make([]*optishop.Zone, len(sorted))
 	for i, e := range sorted {
-		zones[i+1] = e.Info.Zone
+		zones[i] = e.Info.Zone
 	}
-	zones[len(sorted)+1] = checkout
-	points := ZonesToPoints(store.Layout(), zones)
+	points := make([]optishop.FloorPoint, 0, len(sorted)+2)
+	points = append(points, endpoints.Start)
+	points = append(points, ZonesToPoints(layout, zones)...)
+	points = append(points, endpoints.End)
 
 	var res []optishop.FloorPath
-	for i := 1; i < len(zones); i++ {
+	for i := 1; i < len(points); i++ {
 		path := conn.Connect(points[i-1], points[i])
 		if path == nil {
 			return nil, nil, errors.New("route paths: unable to connect two points")
@@ -96,6 +195,21 @@ func RoutePaths(list []*db.ListEntry, store optishop.Store,
 	return res, sorted, nil
 }
 
+// EstimateRoute estimates the travel distance and time
+// for each path in a route, as well as for the entire
+// route.
+func EstimateRoute(store optishop.Store,
+	paths []optishop.FloorPath) ([]optishop.TravelEstimate, optishop.TravelEstimate) {
+	estimator := optishop.NewTravelEstimator(store)
+	var total optishop.TravelEstimate
+	estimates := make([]optishop.TravelEstimate, len(paths))
+	for i, path := range paths {
+		estimates[i] = estimator.Estimate(path)
+		total = total.Add(estimates[i])
+	}
+	return estimates, total
+}
+
 // EntranceAndCheckout finds the entrance and checkout
 // zones for the layout, returning nil if they are not
 // found.
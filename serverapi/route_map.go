@@ -0,0 +1,245 @@
+package serverapi
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	svg "github.com/ajstarks/svgo/float"
+	"github.com/pkg/errors"
+	"github.com/unixpickle/optishop-server/optishop"
+	"github.com/unixpickle/optishop-server/optishop/db"
+	"github.com/unixpickle/optishop-server/optishop/visualize"
+)
+
+// RouteMapSVG renders the part of a map of the store in
+// the viewport with a route drawn on it, the zones of the
+// sorted entries labeled, and numbered markers for each
+// stop along with a legend.
+//
+// The items should correspond to the sorted entries.
+func RouteMapSVG(layout *optishop.Layout, paths []optishop.FloorPath,
+	sorted []*db.ListEntry, items []*ClientListItem, viewport visualize.Viewport,
+	theme *visualize.Theme) []byte {
+	return routeMapSVG(layout, paths, sorted, items, viewport, theme, false)
+}
+
+// AnimatedRouteMapSVG is like RouteMapSVG, but the route
+// is traced out leg by leg with an animation.
+func AnimatedRouteMapSVG(layout *optishop.Layout, paths []optishop.FloorPath,
+	sorted []*db.ListEntry, items []*ClientListItem, viewport visualize.Viewport,
+	theme *visualize.Theme) []byte {
+	return routeMapSVG(layout, paths, sorted, items, viewport, theme, true)
+}
+
+func routeMapSVG(layout *optishop.Layout, paths []optishop.FloorPath,
+	sorted []*db.ListEntry, items []*ClientListItem, viewport visualize.Viewport,
+	theme *visualize.Theme, animate bool) []byte {
+	var imageData bytes.Buffer
+	canvas := svg.New(&imageData)
+
+	theme.StartSVG(canvas, viewport, "Route map")
+
+	visualize.MultiFloorLoopViewport(layout, viewport, func(f *optishop.Floor, x, y float64) {
+		theme.DrawFloorPolygons(canvas, f, x, y)
+	})
+	if animate {
+		theme.DrawAnimatedRoute(canvas, layout, paths)
+	} else {
+		for _, path := range paths {
+			theme.DrawFloorPath(canvas, layout, path)
+			theme.DrawPathArrows(canvas, layout, path)
+		}
+	}
+	for _, path := range paths {
+		theme.DrawPathPortals(canvas, layout, path)
+	}
+	fontSize := 2 * theme.FontSize(layout)
+	visualize.MultiFloorLoopViewport(layout, viewport, func(f *optishop.Floor, x, y float64) {
+		zones := routeLabelZones(layout, sorted, layout.FloorIndex(f))
+		theme.DrawZoneLabels(canvas, zones, x, y, fontSize)
+	})
+	stops := RouteStops(layout, sorted, items, fontSize)
+	theme.DrawStops(canvas, layout, stops)
+	theme.DrawLegend(canvas, viewport, stops)
+
+	canvas.End()
+
+	return dynamicSizeSVG(imageData.Bytes())
+}
+
+// RouteMapPNG is like RouteMapSVG, but it produces a PNG
+// image that is width pixels wide.
+func RouteMapPNG(layout *optishop.Layout, paths []optishop.FloorPath,
+	sorted []*db.ListEntry, items []*ClientListItem, viewport visualize.Viewport,
+	theme *visualize.Theme, width int) ([]byte, error) {
+	canvas, err := visualize.NewRasterCanvas(viewport, width, theme)
+	if err != nil {
+		return nil, err
+	}
+
+	visualize.MultiFloorLoopViewport(layout, viewport, func(f *optishop.Floor, x, y float64) {
+		canvas.DrawFloorPolygons(f, x, y)
+	})
+	for _, path := range paths {
+		canvas.DrawFloorPath(layout, path)
+		canvas.DrawPathArrows(layout, path)
+	}
+	for _, path := range paths {
+		canvas.DrawPathPortals(layout, path)
+	}
+	fontSize := 2 * theme.FontSize(layout)
+	visualize.MultiFloorLoopViewport(layout, viewport, func(f *optishop.Floor, x, y float64) {
+		zones := routeLabelZones(layout, sorted, layout.FloorIndex(f))
+		canvas.DrawZoneLabels(zones, x, y, fontSize)
+	})
+	stops := RouteStops(layout, sorted, items, fontSize)
+	canvas.DrawStops(layout, stops)
+	canvas.DrawLegend(stops)
+
+	var data bytes.Buffer
+	if err := canvas.EncodePNG(&data); err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+// RouteStops creates a numbered stop for each of the
+// sorted entries, labeled with the name of the product.
+//
+// Stops are placed above the zone labels, which are drawn
+// with the given font size, so that both remain legible.
+func RouteStops(layout *optishop.Layout, sorted []*db.ListEntry, items []*ClientListItem,
+	fontSize float64) []*visualize.Stop {
+	stops := make([]*visualize.Stop, 0, len(sorted))
+	for i, entry := range sorted {
+		zone := entry.Info.Zone
+		if zone == nil || layout.ZoneFloor(zone) == -1 {
+			continue
+		}
+		stop := &visualize.Stop{
+			Number: i + 1,
+			Floor:  layout.ZoneFloor(zone),
+			Location: optishop.Point{
+				X: zone.Location.X,
+				Y: zone.Location.Y - fontSize*1.5,
+			},
+		}
+		if i < len(items) {
+			stop.Label = items[i].Name
+		}
+		stops = append(stops, stop)
+	}
+	return stops
+}
+
+// routeLabelZones gets the zones to label on a floor of a
+// route map, which are drawn as large labels.
+func routeLabelZones(layout *optishop.Layout, sorted []*db.ListEntry,
+	floor int) []*optishop.Zone {
+	var zones []*optishop.Zone
+	for _, entry := range sorted {
+		if layout.ZoneFloor(entry.Info.Zone) == floor {
+			z := *entry.Info.Zone
+			z.Specific = false
+			zones = append(zones, &z)
+		}
+	}
+	return zones
+}
+
+// MapSVG renders the part of a map of the store in the
+// viewport.
+func MapSVG(layout *optishop.Layout, viewport visualize.Viewport,
+	theme *visualize.Theme) []byte {
+	var imageData bytes.Buffer
+	canvas := svg.New(&imageData)
+	theme.StartSVG(canvas, viewport, "Store map")
+	theme.DrawFloorsViewport(canvas, layout, viewport)
+	canvas.End()
+	return dynamicSizeSVG(imageData.Bytes())
+}
+
+// MapPNG is like MapSVG, but it produces a PNG image that
+// is width pixels wide.
+func MapPNG(layout *optishop.Layout, viewport visualize.Viewport, theme *visualize.Theme,
+	width int) ([]byte, error) {
+	canvas, err := visualize.NewRasterCanvas(viewport, width, theme)
+	if err != nil {
+		return nil, err
+	}
+	canvas.DrawFloors(layout)
+	var data bytes.Buffer
+	if err := canvas.EncodePNG(&data); err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+// HeatmapSVG renders the part of a map of the store in the
+// viewport, shaded by how frequently each zone is visited.
+func HeatmapSVG(layout *optishop.Layout, viewport visualize.Viewport, theme *visualize.Theme,
+	freqs []*visualize.ZoneFrequency) []byte {
+	var imageData bytes.Buffer
+	canvas := svg.New(&imageData)
+	theme.StartSVG(canvas, viewport, "Aisle popularity")
+	theme.DrawHeatmap(canvas, layout, viewport, freqs)
+	canvas.End()
+	return dynamicSizeSVG(imageData.Bytes())
+}
+
+// LayoutDiffSVG renders a corrected layout with numbered
+// markers at each change, and a legend describing the
+// changes.
+func LayoutDiffSVG(layout *optishop.Layout, viewport visualize.Viewport,
+	theme *visualize.Theme, changes []*optishop.LayoutChange) []byte {
+	var stops []*visualize.Stop
+	for _, change := range changes {
+		if change.Location == nil || change.Floor < 0 || change.Floor >= len(layout.Floors) {
+			continue
+		}
+		stops = append(stops, &visualize.Stop{
+			Number:   len(stops) + 1,
+			Floor:    change.Floor,
+			Location: *change.Location,
+			Label:    change.Message,
+		})
+	}
+
+	var imageData bytes.Buffer
+	canvas := svg.New(&imageData)
+	theme.StartSVG(canvas, viewport, "Layout corrections")
+	theme.DrawFloorsViewport(canvas, layout, viewport)
+	theme.DrawStops(canvas, layout, stops)
+	theme.DrawLegend(canvas, viewport, stops)
+	canvas.End()
+	return dynamicSizeSVG(imageData.Bytes())
+}
+
+// Bounds on the width of PNG images, in pixels.
+const (
+	DefaultImageWidth = 1000
+	MaxImageWidth     = 4000
+)
+
+// ParseImageWidth determines the width of a PNG image from
+// the "width" parameter of a request.
+func ParseImageWidth(r *http.Request) (int, error) {
+	widthStr := r.FormValue("width")
+	if widthStr == "" {
+		return DefaultImageWidth, nil
+	}
+	width, err := strconv.Atoi(widthStr)
+	if err != nil || width <= 0 || width > MaxImageWidth {
+		return 0, errors.New("parse image width: invalid width")
+	}
+	return width, nil
+}
+
+// dynamicSizeSVG removes the width/height attributes so
+// that an SVG has a dynamic size.
+func dynamicSizeSVG(data []byte) []byte {
+	expr := regexp.MustCompile(`<svg width="[0-9\.]*" height="[0-9\.]*"`)
+	return expr.ReplaceAll(data, []byte("<svg"))
+}
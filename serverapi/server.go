@@ -3,17 +3,13 @@ package serverapi
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/http"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
-	svg "github.com/ajstarks/svgo/float"
-	"github.com/unixpickle/optishop-server/optishop/visualize"
-
 	"github.com/pkg/errors"
 	"github.com/unixpickle/optishop-server/optishop"
 	"github.com/unixpickle/optishop-server/optishop/db"
@@ -24,9 +20,26 @@ type Server struct {
 	NumProxies int
 	LocalMode  bool
 
+	// RouteTimeout limits how long route planning may take
+	// for a single request. If 0, there is no limit.
+	RouteTimeout time.Duration
+
+	// Admins lists the usernames of users who may access
+	// administrative endpoints.
+	Admins []string
+
 	DB         db.DB
 	Sources    map[string]optishop.StoreSource
 	StoreCache *StoreCache
+
+	// Overrides, if non-nil, stores corrections to store
+	// layouts which administrators can edit. It should be
+	// the same as StoreCache.Overrides.
+	Overrides *LayoutOverrides
+
+	// Popularity caches the zone popularity used by the
+	// admin heatmap.
+	Popularity *PopularityCache
 }
 
 func (s *Server) AddRoutes() {
@@ -34,19 +47,42 @@ func (s *Server) AddRoutes() {
 	http.HandleFunc("/list", s.AuthHandler(s.StoreHandler(s.HandleList)))
 	http.HandleFunc("/login", s.HandleLogin)
 	http.HandleFunc("/logout", s.HandleLogout)
+	http.HandleFunc("/print", s.AuthHandler(s.StoreHandler(s.HandlePrint)))
 	http.HandleFunc("/route", s.AuthHandler(s.StoreHandler(s.HandleRoute)))
 	http.HandleFunc("/signup", s.HandleSignup)
 	http.HandleFunc("/api/additem",
 		s.AuthHandler(s.StoreHandler(s.HandleAddItemAPI)))
 	http.HandleFunc("/api/addstore", s.AuthHandler(s.HandleAddStoreAPI))
+	http.HandleFunc("/api/admin/heatmap",
+		s.AdminHandler(s.StoreHandler(s.HandleHeatmapAPI)))
+	http.HandleFunc("/api/admin/layoutdiff",
+		s.AdminHandler(s.StoreHandler(s.HandleLayoutDiffAPI)))
+	http.HandleFunc("/api/admin/layoutedits",
+		s.AdminHandler(s.StoreHandler(s.HandleLayoutEditsAPI)))
+	http.HandleFunc("/api/admin/sources", s.AdminHandler(s.HandleSourcesAPI))
+	http.HandleFunc("/api/checkitem",
+		s.AuthHandler(s.StoreHandler(s.HandleCheckItemAPI)))
 	http.HandleFunc("/api/chpass", s.AuthHandler(s.HandleChpassAPI))
+	http.HandleFunc("/api/itemhandling",
+		s.AuthHandler(s.StoreHandler(s.HandleItemHandlingAPI)))
+	// Directions were served here before /api/route existed.
+	http.HandleFunc("/api/directions",
+		s.AuthHandler(s.StoreHandler(s.HandleRouteAPI)))
+	http.HandleFunc("/api/hittest", s.AuthHandler(s.StoreHandler(s.HandleHitTestAPI)))
 	http.HandleFunc("/api/inventoryquery",
 		s.AuthHandler(s.StoreHandler(s.HandleInventoryQueryAPI)))
 	http.HandleFunc("/api/list", s.AuthHandler(s.StoreHandler(s.HandleListAPI)))
 	http.HandleFunc("/api/map", s.AuthHandler(s.StoreHandler(s.HandleMapAPI)))
+	http.HandleFunc("/api/maptheme", s.AuthHandler(s.HandleMapThemeAPI))
 	http.HandleFunc("/api/removeitem",
 		s.AuthHandler(s.StoreHandler(s.HandleRemoveItemAPI)))
 	http.HandleFunc("/api/removestore", s.AuthHandler(s.HandleRemoveStoreAPI))
+	http.HandleFunc("/api/reroute", s.AuthHandler(s.StoreHandler(s.HandleRerouteAPI)))
+	http.HandleFunc("/api/route", s.AuthHandler(s.StoreHandler(s.HandleRouteAPI)))
+	http.HandleFunc("/api/routeendpoints",
+		s.AuthHandler(s.StoreHandler(s.HandleRouteEndpointsAPI)))
+	http.HandleFunc("/api/routemap", s.AuthHandler(s.StoreHandler(s.HandleRouteMapAPI)))
+	http.HandleFunc("/api/routingprofile", s.AuthHandler(s.HandleRoutingProfileAPI))
 	http.HandleFunc("/api/sort", s.AuthHandler(s.StoreHandler(s.HandleSortAPI)))
 	http.HandleFunc("/api/storequery", s.AuthHandler(s.HandleStoreQueryAPI))
 	http.HandleFunc("/api/stores", s.AuthHandler(s.HandleStoresAPI))
@@ -133,6 +169,40 @@ func (s *Server) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	LogRequest(r, "logout")
 }
 
+func (s *Server) HandlePrint(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserKey).(db.UserID)
+	storeID := r.Context().Value(StoreIDKey).(db.StoreID)
+	store := r.Context().Value(StoreKey).(optishop.Store)
+
+	record, err := s.DB.Store(userID, storeID)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	plan, err := s.planRoute(r)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+
+	theme, err := s.UserMapTheme(userID)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+
+	title := "Shopping List: " + record.Info.StoreName
+	pageData, err := PrintableList(title, store.Layout(), plan.Paths, plan.Sorted, plan.List,
+		theme)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	w.Write(pageData)
+
+	LogRequest(r, "printed list of %d entries", len(plan.Sorted))
+}
+
 func (s *Server) HandleRoute(w http.ResponseWriter, r *http.Request) {
 	pageData, err := ioutil.ReadFile(filepath.Join(s.AssetDir, "route.html"))
 	if err != nil {
@@ -140,68 +210,94 @@ func (s *Server) HandleRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID := r.Context().Value(UserKey).(db.UserID)
-	storeID := r.Context().Value(StoreIDKey).(db.StoreID)
 	store := r.Context().Value(StoreKey).(optishop.Store)
-
-	entries, err := s.DB.ListEntries(userID, storeID)
+	plan, err := s.planRoute(r)
 	if err != nil {
 		s.ServeError(w, r, err)
 		return
 	}
 
-	connector := optishop.NewFloorConnectorCached(store.Layout())
-	paths, sorted, err := RoutePaths(entries, store, connector)
+	viewport, err := ParseViewport(store.Layout(), plan.Paths, r)
 	if err != nil {
 		s.ServeError(w, r, err)
 		return
 	}
-	clientList, err := listEntriesToClientListItems(store, sorted)
+
+	theme, err := s.UserMapTheme(r.Context().Value(UserKey).(db.UserID))
 	if err != nil {
 		s.ServeError(w, r, err)
 		return
 	}
 
-	var imageData bytes.Buffer
-	canvas := svg.New(&imageData)
+	renderMap := RouteMapSVG
+	if r.FormValue("animate") == "true" {
+		renderMap = AnimatedRouteMapSVG
+	}
+	data := renderMap(store.Layout(), plan.Paths, plan.Sorted, plan.List, viewport, theme)
+	listData, _ := json.Marshal(plan.List)
+	pageData = bytes.Replace(pageData, []byte("INSERT_IMAGE_HERE"), data, 1)
+	pageData = bytes.Replace(pageData, []byte("INSERT_LIST_HERE"), listData, 1)
 
-	width, height, _ := visualize.MultiFloorGeometry(store.Layout())
-	canvas.Start(width, height, fmt.Sprintf("viewBox=\"0 0 %f %f\"", width, height))
+	w.Write(pageData)
 
-	visualize.MultiFloorLoop(store.Layout(), func(f *optishop.Floor, x, y float64) {
-		visualize.DrawFloorPolygons(canvas, f, x, y)
-	})
-	for _, path := range paths {
-		visualize.DrawFloorPath(canvas, store.Layout(), path)
-	}
-	fontSize := 2 * width * visualize.FontSizeFrac
-	visualize.MultiFloorLoop(store.Layout(), func(f *optishop.Floor, x, y float64) {
-		floorIdx := store.Layout().FloorIndex(f)
-		var zones []*optishop.Zone
-		for _, entry := range sorted {
-			if store.Layout().ZoneFloor(entry.Info.Zone) == floorIdx {
-				z := *entry.Info.Zone
-				z.Specific = false
-				zones = append(zones, &z)
-			}
-		}
-		visualize.DrawZoneLabels(canvas, zones, x, y, fontSize)
-	})
+	LogRequest(r, "planned route for %d entries", len(plan.Sorted))
+}
 
-	canvas.End()
+// A routePlan is the result of planning a route for a
+// request.
+type routePlan struct {
+	Paths  []optishop.FloorPath
+	Sorted []*db.ListEntry
+	List   []*ClientListItem
 
-	// Remove the width/height attributes so that the SVG
-	// has a dynamic size.
-	expr := regexp.MustCompile(`<svg width="[0-9\.]*" height="[0-9\.]*"`)
-	data := expr.ReplaceAll(imageData.Bytes(), []byte("<svg"))
+	// Estimates has one estimate per path.
+	Estimates []optishop.TravelEstimate
+	Total     optishop.TravelEstimate
+}
 
-	listData, _ := json.Marshal(clientList)
-	pageData = bytes.Replace(pageData, []byte("INSERT_IMAGE_HERE"), data, 1)
-	pageData = bytes.Replace(pageData, []byte("INSERT_LIST_HERE"), listData, 1)
+// planRoute plans a route through the user's list for a
+// request, honoring the user's routing profile, the
+// requested endpoints, and the remaining entries.
+func (s *Server) planRoute(r *http.Request) (*routePlan, error) {
+	userID := r.Context().Value(UserKey).(db.UserID)
+	storeID := r.Context().Value(StoreIDKey).(db.StoreID)
+	store := r.Context().Value(StoreKey).(optishop.Store)
 
-	w.Write(pageData)
+	entries, err := s.DB.ListEntries(userID, storeID)
+	if err != nil {
+		return nil, err
+	}
+	entries = RemainingEntries(entries, r)
+
+	connector, err := s.FloorConnector(userID, store.Layout())
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := ParseRouteEndpoints(store.Layout(), r)
+	if err != nil {
+		return nil, err
+	}
 
-	LogRequest(r, "planned route for %d entries", len(entries))
+	ctx, cancel := s.RouteContext(r)
+	defer cancel()
+	paths, sorted, err := RoutePaths(ctx, entries, store.Layout(), connector, endpoints)
+	if err != nil {
+		return nil, err
+	}
+	clientList, err := listEntriesToClientListItems(store, sorted)
+	if err != nil {
+		return nil, err
+	}
+
+	estimates, total := EstimateRoute(store, paths)
+
+	return &routePlan{
+		Paths:     paths,
+		Sorted:    sorted,
+		List:      clientList,
+		Estimates: estimates,
+		Total:     total,
+	}, nil
 }
 
 func (s *Server) HandleSignup(w http.ResponseWriter, r *http.Request) {
@@ -459,6 +555,27 @@ func (s *Server) HandleInventoryQueryAPI(w http.ResponseWriter, r *http.Request)
 	LogRequest(r, "performed inventory query: %s", query)
 }
 
+func (s *Server) HandleItemHandlingAPI(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(UserKey).(db.UserID)
+	storeID := r.Context().Value(StoreIDKey).(db.StoreID)
+	item := db.ListEntryID(r.FormValue("item"))
+	cold := r.FormValue("cold") == "true"
+	fragile := r.FormValue("fragile") == "true"
+
+	err := s.DB.ModifyListEntry(user, storeID, item, func(info *db.ListEntryInfo) {
+		info.Cold = cold
+		info.Fragile = fragile
+	})
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+
+	LogRequest(r, "set handling for item %s: cold=%v fragile=%v", item, cold, fragile)
+
+	s.HandleListAPI(w, r)
+}
+
 func (s *Server) HandleListAPI(w http.ResponseWriter, r *http.Request) {
 	items, err := s.getClientListItems(r)
 	if err != nil {
@@ -495,33 +612,242 @@ func listEntriesToClientListItems(store optishop.Store,
 		if entry.Info.Zone != nil {
 			item.ZoneName = entry.Info.Zone.Name
 		}
+		item.Cold = entry.Info.Cold
+		item.Fragile = entry.Info.Fragile
 		results = append(results, item)
 	}
 	return results, nil
 }
 
-func (s *Server) HandleMapAPI(w http.ResponseWriter, r *http.Request) {
+func (s *Server) HandleHeatmapAPI(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(UserKey).(db.UserID)
+	storeID := r.Context().Value(StoreIDKey).(db.StoreID)
 	store := r.Context().Value(StoreKey).(optishop.Store)
 
-	var imageData bytes.Buffer
-	canvas := svg.New(&imageData)
+	record, err := s.DB.Store(user, storeID)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	freqs, err := s.Popularity.ZonePopularity(record.Info)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+
+	if r.FormValue("format") == "json" {
+		ServeObject(w, r, NewClientZoneFrequencies(freqs))
+	} else {
+		viewport, err := ParseViewport(store.Layout(), nil, r)
+		if err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		theme, err := s.UserMapTheme(user)
+		if err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		w.Header().Set("content-type", "image/svg+xml")
+		w.Write(HeatmapSVG(store.Layout(), viewport, theme, freqs))
+	}
+
+	LogRequest(r, "served heatmap for %d zones", len(freqs))
+}
+
+func (s *Server) HandleSourcesAPI(w http.ResponseWriter, r *http.Request) {
+	if name := r.FormValue("retry"); name != "" {
+		source, ok := s.Sources[name].(*LazySource)
+		if !ok {
+			s.ServeError(w, r, errors.New("no such lazily initialized source: "+name))
+			return
+		}
+		if err := source.Retry(); err != nil {
+			LogRequest(r, "retry failed for source %s: %s", name, err.Error())
+		}
+	}
+	ServeObject(w, r, SourceStatuses(s.Sources))
+}
+
+func (s *Server) HandleLayoutEditsAPI(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(UserKey).(db.UserID)
+	storeID := r.Context().Value(StoreIDKey).(db.StoreID)
+
+	if s.Overrides == nil {
+		s.ServeError(w, r, errors.New("layout overrides are not enabled"))
+		return
+	}
+	record, err := s.DB.Store(user, storeID)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	info := record.Info
+
+	if r.Method == "POST" {
+		var edits []*optishop.LayoutEdit
+		if err := json.Unmarshal([]byte(r.FormValue("edits")), &edits); err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		original, err := s.StoreCache.GetOriginalLayout(info.SourceName, info.StoreData)
+		if err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		if _, err := optishop.ApplyLayoutEdits(original, edits); err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		override := &LayoutOverride{
+			SourceName:   info.SourceName,
+			StoreName:    info.StoreName,
+			StoreAddress: info.StoreAddress,
+			Edits:        edits,
+			Modified:     time.Now(),
+		}
+		if err := s.Overrides.Put(override); err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		if err := s.StoreCache.Invalidate(info.SourceName, info.StoreData); err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		LogRequest(r, "saved %d layout edits for store %s", len(edits), info.StoreName)
+		ServeObject(w, r, NewClientLayoutEdits(edits))
+		return
+	}
+
+	override, err := s.Overrides.Get(info.SourceName, info.StoreName, info.StoreAddress)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	var edits []*optishop.LayoutEdit
+	if override != nil {
+		edits = override.Edits
+	}
+	ServeObject(w, r, NewClientLayoutEdits(edits))
+}
 
-	width, height, _ := visualize.MultiFloorGeometry(store.Layout())
-	canvas.Start(width, height, fmt.Sprintf("viewBox=\"0 0 %f %f\"", width, height))
-	visualize.DrawFloors(canvas, store.Layout())
-	canvas.End()
+func (s *Server) HandleLayoutDiffAPI(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(UserKey).(db.UserID)
+	storeID := r.Context().Value(StoreIDKey).(db.StoreID)
+	store := r.Context().Value(StoreKey).(optishop.Store)
 
-	// Remove the width/height attributes so that the SVG
-	// has a dynamic size.
-	expr := regexp.MustCompile(`<svg width="[0-9\.]*" height="[0-9\.]*"`)
-	data := expr.ReplaceAll(imageData.Bytes(), []byte("<svg"))
+	record, err := s.DB.Store(user, storeID)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	original, err := s.StoreCache.GetOriginalLayout(record.Info.SourceName,
+		record.Info.StoreData)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	changes := optishop.DiffLayouts(original, store.Layout())
 
+	if r.FormValue("format") == "json" {
+		ServeObject(w, r, NewClientLayoutChanges(changes))
+		return
+	}
+	viewport, err := ParseViewport(store.Layout(), nil, r)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	theme, err := s.UserMapTheme(user)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
 	w.Header().Set("content-type", "image/svg+xml")
-	w.Write(data)
+	w.Write(LayoutDiffSVG(store.Layout(), viewport, theme, changes))
+}
+
+func (s *Server) HandleHitTestAPI(w http.ResponseWriter, r *http.Request) {
+	store := r.Context().Value(StoreKey).(optishop.Store)
+
+	point, err := ParseMapPoint(store.Layout(), r)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+
+	numZones := DefaultHitTestZones
+	if numStr := r.FormValue("zones"); numStr != "" {
+		numZones, err = strconv.Atoi(numStr)
+		if err != nil || numZones < 1 || numZones > MaxHitTestZones {
+			s.ServeError(w, r, errors.New("invalid number of zones"))
+			return
+		}
+	}
+
+	ServeObject(w, r, NewClientHitTest(store.Layout(), point, numZones))
+	LogRequest(r, "hit tested floor %d at (%.2f, %.2f)", point.Floor, point.X, point.Y)
+}
+
+func (s *Server) HandleMapAPI(w http.ResponseWriter, r *http.Request) {
+	store := r.Context().Value(StoreKey).(optishop.Store)
+
+	viewport, err := ParseViewport(store.Layout(), nil, r)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+
+	theme, err := s.UserMapTheme(r.Context().Value(UserKey).(db.UserID))
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+
+	if r.FormValue("format") == "png" {
+		width, err := ParseImageWidth(r)
+		if err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		data, err := MapPNG(store.Layout(), viewport, theme, width)
+		if err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		w.Header().Set("content-type", "image/png")
+		w.Write(data)
+	} else {
+		w.Header().Set("content-type", "image/svg+xml")
+		w.Write(MapSVG(store.Layout(), viewport, theme))
+	}
 
 	LogRequest(r, "served map")
 }
 
+func (s *Server) HandleMapThemeAPI(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(UserKey).(db.UserID)
+
+	if name := r.FormValue("theme"); name != "" {
+		if _, err := MapTheme(name); err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		if err := s.DB.SetUserMetadata(user, MapThemeKey, name); err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		LogRequest(r, "set map theme: %s", name)
+	}
+
+	name, err := s.MapThemeName(user)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	ServeObject(w, r, name)
+}
+
 func (s *Server) HandleRemoveItemAPI(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value(UserKey).(db.UserID)
 	store := db.StoreID(r.FormValue("store"))
@@ -534,6 +860,18 @@ func (s *Server) HandleRemoveItemAPI(w http.ResponseWriter, r *http.Request) {
 	s.HandleListAPI(w, r)
 }
 
+func (s *Server) HandleCheckItemAPI(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(UserKey).(db.UserID)
+	store := db.StoreID(r.FormValue("store"))
+	item := db.ListEntryID(r.FormValue("item"))
+	if err := s.DB.CheckOffListEntry(user, store, item); err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	LogRequest(r, "checked off item: %s", item)
+	s.HandleListAPI(w, r)
+}
+
 func (s *Server) HandleRemoveStoreAPI(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value(UserKey).(db.UserID)
 	store := db.StoreID(r.FormValue("store"))
@@ -545,6 +883,128 @@ func (s *Server) HandleRemoveStoreAPI(w http.ResponseWriter, r *http.Request) {
 	s.HandleStoresAPI(w, r)
 }
 
+func (s *Server) HandleRerouteAPI(w http.ResponseWriter, r *http.Request) {
+	store := r.Context().Value(StoreKey).(optishop.Store)
+	plan, err := s.planRoute(r)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	viewport, err := ParseViewport(store.Layout(), plan.Paths, r)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	theme, err := s.UserMapTheme(r.Context().Value(UserKey).(db.UserID))
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	ServeObject(w, r, map[string]interface{}{
+		"list":  plan.List,
+		"map":   string(RouteMapSVG(store.Layout(), plan.Paths, plan.Sorted, plan.List, viewport, theme)),
+		"total": NewClientEstimate(plan.Total),
+	})
+	LogRequest(r, "re-planned route for %d entries", len(plan.Sorted))
+}
+
+func (s *Server) HandleRouteAPI(w http.ResponseWriter, r *http.Request) {
+	store := r.Context().Value(StoreKey).(optishop.Store)
+	plan, err := s.planRoute(r)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	ServeObject(w, r, map[string]interface{}{
+		"legs":  NewClientLegs(store.Layout(), plan.Paths, plan.Estimates, plan.List),
+		"total": NewClientEstimate(plan.Total),
+	})
+	LogRequest(r, "served route for %d entries", len(plan.Sorted))
+}
+
+func (s *Server) HandleRouteMapAPI(w http.ResponseWriter, r *http.Request) {
+	store := r.Context().Value(StoreKey).(optishop.Store)
+	plan, err := s.planRoute(r)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	viewport, err := ParseViewport(store.Layout(), plan.Paths, r)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+
+	theme, err := s.UserMapTheme(r.Context().Value(UserKey).(db.UserID))
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+
+	animate := r.FormValue("animate") == "true"
+	if r.FormValue("format") == "png" {
+		if animate {
+			s.ServeError(w, r, errors.New("animation is only supported for SVG maps"))
+			return
+		}
+		width, err := ParseImageWidth(r)
+		if err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		data, err := RouteMapPNG(store.Layout(), plan.Paths, plan.Sorted, plan.List, viewport,
+			theme, width)
+		if err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		w.Header().Set("content-type", "image/png")
+		w.Write(data)
+	} else if animate {
+		w.Header().Set("content-type", "image/svg+xml")
+		w.Write(AnimatedRouteMapSVG(store.Layout(), plan.Paths, plan.Sorted, plan.List, viewport,
+			theme))
+	} else {
+		w.Header().Set("content-type", "image/svg+xml")
+		w.Write(RouteMapSVG(store.Layout(), plan.Paths, plan.Sorted, plan.List, viewport, theme))
+	}
+
+	LogRequest(r, "served route map for %d entries", len(plan.Sorted))
+}
+
+func (s *Server) HandleRouteEndpointsAPI(w http.ResponseWriter, r *http.Request) {
+	store := r.Context().Value(StoreKey).(optishop.Store)
+	layout := store.Layout()
+	ServeObject(w, r, map[string]interface{}{
+		"entrances": NewClientZones(layout, Entrances(layout)),
+		"checkouts": NewClientZones(layout, Checkouts(layout)),
+	})
+	LogRequest(r, "served route endpoints")
+}
+
+func (s *Server) HandleRoutingProfileAPI(w http.ResponseWriter, r *http.Request) {
+	user := r.Context().Value(UserKey).(db.UserID)
+
+	if name := r.FormValue("profile"); name != "" {
+		if _, err := RoutingProfile(name, &optishop.Layout{}); err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		if err := s.DB.SetUserMetadata(user, RoutingProfileKey, name); err != nil {
+			s.ServeError(w, r, err)
+			return
+		}
+		LogRequest(r, "set routing profile: %s", name)
+	}
+
+	name, err := s.RoutingProfileName(user)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	ServeObject(w, r, name)
+}
+
 func (s *Server) HandleSortAPI(w http.ResponseWriter, r *http.Request) {
 	user := r.Context().Value(UserKey).(db.UserID)
 	storeID := r.Context().Value(StoreIDKey).(db.StoreID)
@@ -556,7 +1016,21 @@ func (s *Server) HandleSortAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	entries, err := SortEntries(list, store, optishop.NewFloorConnectorCached(store.Layout()))
+	connector, err := s.FloorConnector(user, store.Layout())
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+
+	endpoints, err := ParseRouteEndpoints(store.Layout(), r)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+
+	ctx, cancel := s.RouteContext(r)
+	defer cancel()
+	paths, entries, err := RoutePaths(ctx, list, store.Layout(), connector, endpoints)
 	if err != nil {
 		s.ServeError(w, r, err)
 		return
@@ -571,9 +1045,18 @@ func (s *Server) HandleSortAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	LogRequest(r, "sorted %d entries", len(entries))
+	items, err := s.getClientListItems(r)
+	if err != nil {
+		s.ServeError(w, r, err)
+		return
+	}
+	_, total := EstimateRoute(store, paths)
+	ServeObject(w, r, map[string]interface{}{
+		"list":  items,
+		"total": NewClientEstimate(total),
+	})
 
-	s.HandleListAPI(w, r)
+	LogRequest(r, "sorted %d entries", len(entries))
 }
 
 func (s *Server) HandleStoreQueryAPI(w http.ResponseWriter, r *http.Request) {
@@ -590,6 +1073,11 @@ func (s *Server) HandleStoreQueryAPI(w http.ResponseWriter, r *http.Request) {
 	for name, source := range s.Sources {
 		results, err := source.QueryStores(query)
 		if err != nil {
+			if _, ok := errors.Cause(err).(*SourceUnavailableError); ok {
+				// Other sources may still have results.
+				LogRequest(r, "skipping store source: %s", err.Error())
+				continue
+			}
 			s.ServeError(w, r, err)
 			return
 		}
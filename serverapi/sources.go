@@ -1,18 +1,311 @@
 package serverapi
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
 	"github.com/unixpickle/optishop-server/optishop"
+	"github.com/unixpickle/optishop-server/optishop/handmade"
 	"github.com/unixpickle/optishop-server/optishop/target"
 )
 
-// LoadStoreSources creates a map of supported store
-// sources.
-func LoadStoreSources() (map[string]optishop.StoreSource, error) {
-	client, err := target.NewClient()
+// SourceRetryInterval is the minimum amount of time
+// between attempts to initialize a store source.
+const SourceRetryInterval = time.Minute
+
+// A SourceFactory creates a store source from the options
+// in its configuration.
+type SourceFactory func(options json.RawMessage) (optishop.StoreSource, error)
+
+var sourceFactoriesLock sync.RWMutex
+var sourceFactories = map[string]SourceFactory{}
+
+func init() {
+	RegisterStoreSource("target", func(options json.RawMessage) (optishop.StoreSource, error) {
+		client, err := target.NewClient()
+		if err != nil {
+			return nil, err
+		}
+		return &target.StoreSource{Client: client}, nil
+	})
+	RegisterStoreSource("handmade", func(options json.RawMessage) (optishop.StoreSource, error) {
+		var opts struct {
+			Dir string `json:"dir"`
+		}
+		if len(options) > 0 {
+			if err := json.Unmarshal(options, &opts); err != nil {
+				return nil, err
+			}
+		}
+		if opts.Dir == "" {
+			return nil, errors.New("no layout directory specified")
+		}
+		return &handmade.StoreSource{Dir: opts.Dir}, nil
+	})
+}
+
+// RegisterStoreSource makes a type of store source
+// available to source configurations.
+//
+// Registering a type that already exists replaces it.
+func RegisterStoreSource(sourceType string, f SourceFactory) {
+	sourceFactoriesLock.Lock()
+	defer sourceFactoriesLock.Unlock()
+	sourceFactories[sourceType] = f
+}
+
+// A SourceConfig configures a single store source.
+type SourceConfig struct {
+	// Name identifies the source in the database and on the
+	// front-end.
+	Name string `json:"name"`
+
+	// Type is the registered type of the source.
+	// If empty, the type is the same as the name.
+	Type string `json:"type,omitempty"`
+
+	// Options are passed to the source's factory.
+	Options json.RawMessage `json:"options,omitempty"`
+
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// A SourcesConfig lists the store sources to enable.
+type SourcesConfig struct {
+	Sources []*SourceConfig `json:"sources"`
+}
+
+// ReadSourcesConfig reads a JSON sources configuration.
+func ReadSourcesConfig(path string) (*SourcesConfig, error) {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrap(err, "read sources config")
+	}
+	var config SourcesConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, errors.Wrap(err, "read sources config")
+	}
+	return &config, nil
+}
+
+// LoadStoreSources creates a map of the enabled store
+// sources in a configuration.
+//
+// Sources are initialized lazily, so a source which is
+// temporarily unavailable does not prevent other sources
+// from working.
+func LoadStoreSources(config *SourcesConfig) (map[string]optishop.StoreSource, error) {
+	sourceFactoriesLock.RLock()
+	defer sourceFactoriesLock.RUnlock()
+
+	res := map[string]optishop.StoreSource{}
+	for _, sourceConfig := range config.Sources {
+		if sourceConfig.Disabled {
+			continue
+		}
+		if sourceConfig.Name == "" {
+			return nil, errors.New("load store sources: missing source name")
+		}
+		if _, ok := res[sourceConfig.Name]; ok {
+			return nil, errors.New("load store sources: duplicate source: " + sourceConfig.Name)
+		}
+		sourceType := sourceConfig.Type
+		if sourceType == "" {
+			sourceType = sourceConfig.Name
+		}
+		factory, ok := sourceFactories[sourceType]
+		if !ok {
+			return nil, errors.New("load store sources: unknown source type: " + sourceType)
+		}
+		res[sourceConfig.Name] = &LazySource{
+			Name:    sourceConfig.Name,
+			Type:    sourceType,
+			Options: sourceConfig.Options,
+			Factory: factory,
+		}
+	}
+	return res, nil
+}
+
+// A SourceUnavailableError is returned when a LazySource
+// cannot be initialized.
+type SourceUnavailableError struct {
+	Name string
+	Err  error
+}
+
+func (s *SourceUnavailableError) Error() string {
+	return "store source " + s.Name + " is unavailable: " + s.Err.Error()
+}
+
+// A SourceStatus describes the health of a store source.
+type SourceStatus struct {
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	Ready       bool       `json:"ready"`
+	LastError   string     `json:"lastError,omitempty"`
+	LastAttempt *time.Time `json:"lastAttempt,omitempty"`
+	Failures    int        `json:"failures"`
+}
+
+// A LazySource is a store source which is created the
+// first time it is used.
+//
+// If creating the source fails, creation is retried when
+// the source is used again after SourceRetryInterval.
+type LazySource struct {
+	Name    string
+	Type    string
+	Options json.RawMessage
+	Factory SourceFactory
+
+	// initLock is held while the factory runs, so that lock
+	// is free for Status() during slow attempts.
+	initLock sync.Mutex
+
+	lock        sync.Mutex
+	source      optishop.StoreSource
+	lastErr     error
+	lastAttempt time.Time
+	failures    int
+}
+
+// Source gets the underlying source, creating it if
+// necessary.
+func (l *LazySource) Source() (optishop.StoreSource, error) {
+	return l.init(false)
+}
+
+// Retry attempts to create the underlying source if it
+// does not exist yet, ignoring SourceRetryInterval.
+func (l *LazySource) Retry() error {
+	_, err := l.init(true)
+	return err
+}
+
+// Status gets the current health of the source.
+func (l *LazySource) Status() *SourceStatus {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	status := &SourceStatus{
+		Name:     l.Name,
+		Type:     l.Type,
+		Ready:    l.source != nil,
+		Failures: l.failures,
+	}
+	if l.lastErr != nil {
+		status.LastError = l.lastErr.Error()
+	}
+	if !l.lastAttempt.IsZero() {
+		t := l.lastAttempt
+		status.LastAttempt = &t
+	}
+	return status
+}
+
+func (l *LazySource) StoresNear(lat, lon float64) ([]optishop.StoreDesc, error) {
+	source, err := l.Source()
+	if err != nil {
+		return nil, errors.Wrap(err, "stores near")
+	}
+	return source.StoresNear(lat, lon)
+}
+
+func (l *LazySource) QueryStores(query string) ([]optishop.StoreDesc, error) {
+	source, err := l.Source()
+	if err != nil {
+		return nil, errors.Wrap(err, "query stores")
+	}
+	return source.QueryStores(query)
+}
+
+func (l *LazySource) Store(desc optishop.StoreDesc) (optishop.Store, error) {
+	source, err := l.Source()
+	if err != nil {
+		return nil, errors.Wrap(err, "load store")
+	}
+	return source.Store(desc)
+}
+
+func (l *LazySource) MarshalStoreDesc(desc optishop.StoreDesc) ([]byte, error) {
+	source, err := l.Source()
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal store description")
+	}
+	return source.MarshalStoreDesc(desc)
+}
+
+func (l *LazySource) UnmarshalStoreDesc(data []byte) (optishop.StoreDesc, error) {
+	source, err := l.Source()
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshal store description")
+	}
+	return source.UnmarshalStoreDesc(data)
+}
+
+func (l *LazySource) init(force bool) (optishop.StoreSource, error) {
+	if source, done, err := l.cachedResult(force); done {
+		return source, err
+	}
+
+	l.initLock.Lock()
+	defer l.initLock.Unlock()
+
+	// Another attempt may have finished while this one was
+	// waiting for initLock.
+	if source, done, err := l.cachedResult(force); done {
+		return source, err
+	}
+
+	l.lock.Lock()
+	l.lastAttempt = time.Now()
+	l.lock.Unlock()
+
+	source, err := l.Factory(l.Options)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if err != nil {
+		l.lastErr = err
+		l.failures++
+		return nil, &SourceUnavailableError{Name: l.Name, Err: err}
+	}
+	l.source = source
+	l.lastErr = nil
+	return source, nil
+}
+
+// cachedResult gets the result of init() without calling
+// the factory, if possible.
+func (l *LazySource) cachedResult(force bool) (optishop.StoreSource, bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.source != nil {
+		return l.source, true, nil
+	}
+	if !force && l.lastErr != nil && time.Since(l.lastAttempt) < SourceRetryInterval {
+		return nil, true, &SourceUnavailableError{Name: l.Name, Err: l.lastErr}
+	}
+	return nil, false, nil
+}
+
+// SourceStatuses gets the health of every source which
+// supports health checks, sorted by name.
+func SourceStatuses(sources map[string]optishop.StoreSource) []*SourceStatus {
+	res := []*SourceStatus{}
+	for name, source := range sources {
+		if lazy, ok := source.(*LazySource); ok {
+			res = append(res, lazy.Status())
+		} else {
+			res = append(res, &SourceStatus{Name: name, Ready: true})
+		}
 	}
-	return map[string]optishop.StoreSource{
-		"target": &target.StoreSource{Client: client},
-	}, nil
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
 }
@@ -0,0 +1,110 @@
+package serverapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/unixpickle/optishop-server/optishop"
+	"github.com/unixpickle/optishop-server/optishop/handmade"
+)
+
+func TestLazySourceRetry(t *testing.T) {
+	var calls int
+	fail := true
+	source := &LazySource{
+		Name: "test",
+		Type: "test",
+		Factory: func(options json.RawMessage) (optishop.StoreSource, error) {
+			calls++
+			if fail {
+				return nil, errors.New("source is down")
+			}
+			return &handmade.StoreSource{Dir: "layouts"}, nil
+		},
+	}
+
+	if _, err := source.Source(); err == nil {
+		t.Fatal("expected error")
+	} else if _, ok := err.(*SourceUnavailableError); !ok {
+		t.Fatalf("unexpected error type: %T", err)
+	}
+	if _, err := source.Source(); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 factory call within retry interval but got %d", calls)
+	}
+
+	if err := source.Retry(); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 2 {
+		t.Errorf("expected Retry to call the factory, but got %d calls", calls)
+	}
+	status := source.Status()
+	if status.Ready || status.Failures != 2 || status.LastError == "" ||
+		status.LastAttempt == nil {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	// Simulate the retry interval elapsing.
+	source.lock.Lock()
+	source.lastAttempt = time.Now().Add(-SourceRetryInterval)
+	source.lock.Unlock()
+
+	fail = false
+	if _, err := source.Source(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Source(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 factory calls but got %d", calls)
+	}
+	status = source.Status()
+	if !status.Ready || status.Failures != 2 || status.LastError != "" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
+
+func TestLazySourceStatusDuringInit(t *testing.T) {
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	source := &LazySource{
+		Name: "test",
+		Type: "test",
+		Factory: func(options json.RawMessage) (optishop.StoreSource, error) {
+			close(started)
+			<-finish
+			return nil, errors.New("source is down")
+		},
+	}
+	done := make(chan struct{})
+	go func() {
+		source.Source()
+		close(done)
+	}()
+	<-started
+
+	statusDone := make(chan *SourceStatus, 1)
+	go func() {
+		statusDone <- source.Status()
+	}()
+	select {
+	case status := <-statusDone:
+		if status.Ready || status.LastAttempt == nil {
+			t.Errorf("unexpected status: %+v", status)
+		}
+	case <-time.After(time.Second * 5):
+		t.Error("Status() blocked while the factory was running")
+	}
+
+	close(finish)
+	<-done
+	if status := source.Status(); status.Failures != 1 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+}
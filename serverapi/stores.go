@@ -2,6 +2,9 @@ package serverapi
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"log"
 	"net/http"
 	"sync"
 	"time"
@@ -53,11 +56,21 @@ func (s *Server) StoreHandler(h http.HandlerFunc) http.HandlerFunc {
 // A StoreCache uses a cache to quickly retrieve Store
 // objects for serialized store descriptions.
 type StoreCache struct {
+	// Overrides, if non-nil, is used to correct the layouts
+	// of stores before they are cached.
+	Overrides *LayoutOverrides
+
 	sources map[string]optishop.StoreSource
 
 	lock        sync.RWMutex
 	cache       map[cacheKey]optishop.Store
+	originals   map[cacheKey]*optishop.Layout
 	expirations map[cacheKey]time.Time
+
+	// validated records the hashes of layouts which have
+	// been validated, so each layout is only validated once
+	// even as stores expire and are reloaded.
+	validated map[layoutHash]bool
 }
 
 // NewStoreCache creates a StoreCache that will used the
@@ -66,42 +79,185 @@ func NewStoreCache(sources map[string]optishop.StoreSource) *StoreCache {
 	return &StoreCache{
 		sources:     sources,
 		cache:       map[cacheKey]optishop.Store{},
+		originals:   map[cacheKey]*optishop.Layout{},
 		expirations: map[cacheKey]time.Time{},
+		validated:   map[layoutHash]bool{},
 	}
 }
 
 // GetStore looks up a Store for the source name and the
 // serialized optishop.StoreDesc.
+//
+// If the store has a layout override, the store's layout
+// has already been corrected.
 func (s *StoreCache) GetStore(sourceName string, descData []byte) (optishop.Store, error) {
-	source, ok := s.sources[sourceName]
-	if !ok {
-		return nil, errors.New("get store: no such source: " + sourceName)
-	}
-	desc, err := source.UnmarshalStoreDesc(descData)
+	store, _, err := s.getStore(sourceName, descData)
 	if err != nil {
 		return nil, errors.Wrap(err, "get store")
 	}
+	return store, nil
+}
+
+// GetOriginalLayout gets the layout of a store as it was
+// provided by the store's source, before any override.
+func (s *StoreCache) GetOriginalLayout(sourceName string,
+	descData []byte) (*optishop.Layout, error) {
+	_, original, err := s.getStore(sourceName, descData)
+	if err != nil {
+		return nil, errors.Wrap(err, "get original layout")
+	}
+	return original, nil
+}
+
+// getStore looks up a store along with its original
+// layout, reading both from the cache at once so that
+// they always belong to the same cache entry.
+func (s *StoreCache) getStore(sourceName string,
+	descData []byte) (optishop.Store, *optishop.Layout, error) {
+	source, desc, key, err := s.lookup(sourceName, descData)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	key := cacheKey{Source: sourceName, Name: desc.Name(), Address: desc.Address()}
 	s.lock.RLock()
 	existing, ok := s.cache[key]
+	original := s.originals[key]
 	expiration := s.expirations[key]
 	s.lock.RUnlock()
 	if ok && time.Now().Before(expiration) {
-		return existing, nil
+		return existing, original, nil
 	}
 
 	store, err := source.Store(desc)
 	if err != nil {
-		return nil, errors.Wrap(err, "get store")
+		return nil, nil, err
 	}
+	original = store.Layout()
+	s.applyOverride(key, store)
+	hash, hashErr := hashLayout(store.Layout())
 
 	s.lock.Lock()
+	s.removeExpired()
 	s.cache[key] = store
+	s.originals[key] = original
 	s.expirations[key] = time.Now().Add(CacheDeadline)
+	needsValidation := hashErr != nil || !s.validated[hash]
+	if hashErr == nil {
+		s.validated[hash] = true
+	}
 	s.lock.Unlock()
 
-	return store, nil
+	if needsValidation {
+		// Validation rasterizes every floor, so it should not
+		// delay the request.
+		go logLayoutProblems(key, store.Layout())
+	}
+
+	return store, original, nil
+}
+
+// Invalidate removes a store from the cache, so that it
+// is reloaded the next time it is requested.
+//
+// This should be called after a store's override changes.
+func (s *StoreCache) Invalidate(sourceName string, descData []byte) error {
+	_, _, key, err := s.lookup(sourceName, descData)
+	if err != nil {
+		return errors.Wrap(err, "invalidate store")
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.remove(key)
+	return nil
+}
+
+// removeExpired removes every expired store from the
+// cache.
+//
+// The caller must hold s.lock.
+func (s *StoreCache) removeExpired() {
+	now := time.Now()
+	for key, expiration := range s.expirations {
+		if now.After(expiration) {
+			s.remove(key)
+		}
+	}
+}
+
+// remove deletes every cache entry for a store.
+//
+// The caller must hold s.lock.
+func (s *StoreCache) remove(key cacheKey) {
+	delete(s.cache, key)
+	delete(s.originals, key)
+	delete(s.expirations, key)
+}
+
+func (s *StoreCache) lookup(sourceName string,
+	descData []byte) (optishop.StoreSource, optishop.StoreDesc, cacheKey, error) {
+	source, ok := s.sources[sourceName]
+	if !ok {
+		return nil, nil, cacheKey{}, errors.New("no such source: " + sourceName)
+	}
+	desc, err := source.UnmarshalStoreDesc(descData)
+	if err != nil {
+		return nil, nil, cacheKey{}, err
+	}
+	key := cacheKey{Source: sourceName, Name: desc.Name(), Address: desc.Address()}
+	return source, desc, key, nil
+}
+
+// applyOverride corrects the layout of a newly loaded
+// store.
+//
+// Failures are logged rather than returned, since the
+// uncorrected store is still usable.
+func (s *StoreCache) applyOverride(key cacheKey, store optishop.Store) {
+	if s.Overrides == nil {
+		return
+	}
+	override, err := s.Overrides.Get(key.Source, key.Name, key.Address)
+	if err != nil {
+		log.Printf("layout override for %s store %s (%s): %s", key.Source, key.Name,
+			key.Address, err.Error())
+		return
+	} else if override == nil {
+		return
+	}
+	editable, ok := store.(optishop.EditableStore)
+	if !ok {
+		log.Printf("layout override for %s store %s (%s): store is not editable", key.Source,
+			key.Name, key.Address)
+		return
+	}
+	layout, err := override.Apply(store.Layout())
+	if err != nil {
+		log.Printf("layout override for %s store %s (%s): %s", key.Source, key.Name,
+			key.Address, err.Error())
+		return
+	}
+	editable.SetLayout(layout)
+}
+
+func logLayoutProblems(key cacheKey, layout *optishop.Layout) {
+	for _, problem := range optishop.ValidateLayout(layout) {
+		log.Printf("layout warning for %s store %s (%s): %s", key.Source, key.Name,
+			key.Address, problem.String())
+	}
+}
+
+type layoutHash [sha256.Size]byte
+
+// hashLayout hashes the encoded layout.
+//
+// It fails if the layout contains non-finite coordinates,
+// which cannot be encoded.
+func hashLayout(layout *optishop.Layout) (layoutHash, error) {
+	data, err := json.Marshal(layout)
+	if err != nil {
+		return layoutHash{}, err
+	}
+	return sha256.Sum256(data), nil
 }
 
 type cacheKey struct {
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -92,3 +94,15 @@ func LogRequest(r *http.Request, format string, args ...interface{}) {
 	}
 	log.Printf(prefix+format, args...)
 }
+
+// ParseFinite parses a floating-point request parameter,
+// rejecting NaN and infinite values.
+func ParseFinite(s string) (float64, error) {
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	} else if math.IsNaN(x) || math.IsInf(x, 0) {
+		return 0, errors.New("number is not finite: " + s)
+	}
+	return x, nil
+}
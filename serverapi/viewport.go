@@ -0,0 +1,99 @@
+package serverapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/unixpickle/optishop-server/optishop"
+	"github.com/unixpickle/optishop-server/optishop/visualize"
+)
+
+// ParseViewport determines which part of a map to render
+// from the parameters of a request.
+//
+// The "floor" parameter selects a single floor by index,
+// the "leg" parameter crops to the given path of a route,
+// and the "viewport" parameter specifies "x,y,width,height"
+// explicitly.
+// The "zoom", "tileX", and "tileY" parameters further
+// select a tile of the resulting viewport.
+//
+// By default, the entire map is rendered.
+func ParseViewport(l *optishop.Layout, paths []optishop.FloorPath,
+	r *http.Request) (visualize.Viewport, error) {
+	v := visualize.FullViewport(l)
+
+	if floorStr := r.FormValue("floor"); floorStr != "" {
+		floor, err := strconv.Atoi(floorStr)
+		if err != nil || floor < 0 || floor >= len(l.Floors) {
+			return v, &BadRequestError{Message: "parse viewport: invalid floor"}
+		}
+		v = visualize.FloorViewport(l, floor)
+	} else if legStr := r.FormValue("leg"); legStr != "" {
+		leg, err := strconv.Atoi(legStr)
+		if err != nil || leg < 0 || leg >= len(paths) {
+			return v, &BadRequestError{Message: "parse viewport: invalid leg"}
+		}
+		v = visualize.PathViewport(l, paths[leg])
+	} else if viewportStr := r.FormValue("viewport"); viewportStr != "" {
+		var values []float64
+		for _, s := range strings.Split(viewportStr, ",") {
+			x, err := ParseFinite(strings.TrimSpace(s))
+			if err != nil {
+				return v, &BadRequestError{Message: "parse viewport: invalid viewport"}
+			}
+			values = append(values, x)
+		}
+		if len(values) != 4 || values[2] <= 0 || values[3] <= 0 {
+			return v, &BadRequestError{Message: "parse viewport: invalid viewport"}
+		}
+		v = visualize.Viewport{X: values[0], Y: values[1], Width: values[2], Height: values[3]}
+	}
+
+	if zoomStr := r.FormValue("zoom"); zoomStr != "" {
+		var ints [3]int
+		for i, s := range []string{zoomStr, r.FormValue("tileX"), r.FormValue("tileY")} {
+			x, err := strconv.Atoi(s)
+			if err != nil {
+				return v, &BadRequestError{Message: "parse viewport: invalid tile"}
+			}
+			ints[i] = x
+		}
+		tile, ok := v.Tile(ints[0], ints[1], ints[2])
+		if !ok {
+			return v, &BadRequestError{Message: "parse viewport: invalid tile"}
+		}
+		v = tile
+	}
+
+	return v, nil
+}
+
+// Bounds on the number of zones returned by a hit test.
+const (
+	DefaultHitTestZones = 5
+	MaxHitTestZones     = 50
+)
+
+// ParseMapPoint determines a point on a floor from the
+// "x" and "y" parameters of a request, which are given in
+// the coordinates of a rendered map.
+//
+// The optional "floor" parameter selects the floor, which
+// is otherwise inferred from the y coordinate.
+func ParseMapPoint(l *optishop.Layout, r *http.Request) (optishop.FloorPoint, error) {
+	x, err1 := ParseFinite(r.FormValue("x"))
+	y, err2 := ParseFinite(r.FormValue("y"))
+	if err1 != nil || err2 != nil {
+		return optishop.FloorPoint{}, &BadRequestError{Message: "parse map point: invalid coordinates"}
+	}
+	floor := -1
+	if floorStr := r.FormValue("floor"); floorStr != "" {
+		floor, err1 = strconv.Atoi(floorStr)
+		if err1 != nil || floor < 0 || floor >= len(l.Floors) {
+			return optishop.FloorPoint{}, &BadRequestError{Message: "parse map point: invalid floor"}
+		}
+	}
+	return visualize.RenderedFloorPoint(l, optishop.Point{X: x, Y: y}, floor), nil
+}
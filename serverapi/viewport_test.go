@@ -0,0 +1,54 @@
+package serverapi
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+func TestParseViewportNonFinite(t *testing.T) {
+	layout := testingViewportLayout()
+	for _, viewport := range []string{"0,0,NaN,10", "0,0,10,Inf", "NaN,0,10,10", "0,-Inf,10,10"} {
+		r := httptest.NewRequest("GET", "/api/map?viewport="+url.QueryEscape(viewport), nil)
+		_, err := ParseViewport(layout, nil, r)
+		if _, ok := errors.Cause(err).(*BadRequestError); !ok {
+			t.Errorf("viewport %s: expected bad request but got %v", viewport, err)
+		}
+	}
+	r := httptest.NewRequest("GET", "/api/map?viewport=1,2,3,4", nil)
+	if _, err := ParseViewport(layout, nil, r); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseMapPointNonFinite(t *testing.T) {
+	layout := testingViewportLayout()
+	for _, query := range []string{"x=NaN&y=1", "x=1&y=Inf", "x=-Inf&y=1"} {
+		r := httptest.NewRequest("GET", "/api/hittest?"+query, nil)
+		_, err := ParseMapPoint(layout, r)
+		if _, ok := errors.Cause(err).(*BadRequestError); !ok {
+			t.Errorf("query %s: expected bad request but got %v", query, err)
+		}
+	}
+}
+
+func TestServeErrorBadRequest(t *testing.T) {
+	s := &Server{}
+	r := httptest.NewRequest("GET", "/api/map", nil)
+	w := httptest.NewRecorder()
+	s.ServeError(w, r, errors.Wrap(&BadRequestError{Message: "invalid"}, "context"))
+	if w.Code != 400 {
+		t.Errorf("expected status 400 but got %d", w.Code)
+	}
+}
+
+func testingViewportLayout() *optishop.Layout {
+	return &optishop.Layout{
+		Floors: []*optishop.Floor{
+			{Bounds: optishop.Polygon{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}, {X: 0, Y: 10}}},
+		},
+	}
+}
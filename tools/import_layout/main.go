@@ -0,0 +1,97 @@
+// Command import_layout converts SVG or GeoJSON floor
+// plans into a Layout.
+//
+// Each SVG file becomes one floor of the layout, in the
+// order they are given. A GeoJSON file may contain every
+// floor of the layout. The layout JSON is written to
+// standard output, so it can be piped into render_layout,
+// and any problems with the layout are written to standard
+// error.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/optishop-server/optishop"
+	"github.com/unixpickle/optishop-server/optishop/floorplan"
+)
+
+func main() {
+	var format string
+	opts := floorplan.DefaultGeoJSONOptions
+	flag.StringVar(&format, "format", "", "input format (svg or geojson); "+
+		"determined from the file extension by default")
+	flag.StringVar(&opts.Layers.Walls, "walls", opts.Layers.Walls, "layer with the walls")
+	flag.StringVar(&opts.Layers.Shelves, "shelves", opts.Layers.Shelves,
+		"layer with the shelves")
+	flag.StringVar(&opts.Layers.Pads, "pads", opts.Layers.Pads, "layer with the floor pads")
+	flag.StringVar(&opts.Layers.Labels, "labels", opts.Layers.Labels,
+		"layer with the zone labels")
+	flag.StringVar(&opts.LayerProperty, "layer-property", opts.LayerProperty,
+		"GeoJSON feature property naming the layer")
+	flag.StringVar(&opts.NameProperty, "name-property", opts.NameProperty,
+		"GeoJSON feature property with the text of labels")
+	flag.StringVar(&opts.FloorProperty, "floor-property", opts.FloorProperty,
+		"GeoJSON feature property naming the floor")
+	flag.BoolVar(&opts.Planar, "planar", false,
+		"treat GeoJSON coordinates as flat rather than longitude and latitude")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: import_layout [flags] <floor plan> [floor plan ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	layout := &optishop.Layout{}
+	for _, path := range flag.Args() {
+		data, err := ioutil.ReadFile(path)
+		essentials.Must(err)
+
+		fileFormat := format
+		if fileFormat == "" {
+			fileFormat = formatForPath(path)
+		}
+		switch fileFormat {
+		case "svg":
+			floor, err := floorplan.ParseSVG(data, &opts.Layers)
+			if err != nil {
+				essentials.Die(path + ": " + err.Error())
+			}
+			floor.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			layout.Floors = append(layout.Floors, floor)
+		case "geojson":
+			subLayout, err := floorplan.ParseGeoJSON(data, &opts)
+			if err != nil {
+				essentials.Die(path + ": " + err.Error())
+			}
+			layout.Floors = append(layout.Floors, subLayout.Floors...)
+		default:
+			essentials.Die("unknown format for " + path + ": " + fileFormat)
+		}
+	}
+
+	for _, problem := range optishop.ValidateLayout(layout) {
+		fmt.Fprintln(os.Stderr, "warning: "+problem.String())
+	}
+	essentials.Must(json.NewEncoder(os.Stdout).Encode(layout))
+}
+
+func formatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".svg":
+		return "svg"
+	case ".geojson", ".json":
+		return "geojson"
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+// Command lint_layout checks Layouts for problems that
+// would break routing, such as zones inside of obstacles
+// and aisles that cannot be reached from the entrance.
+//
+// Layouts are read as JSON from the files given as
+// arguments, or from standard input if there are no
+// arguments. Problems are written to standard output, and
+// the exit status is 1 if any problems were found.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/optishop-server/optishop"
+)
+
+func main() {
+	var numProblems int
+	if len(os.Args) < 2 {
+		numProblems = lintLayout("", os.Stdin)
+	} else {
+		for _, path := range os.Args[1:] {
+			f, err := os.Open(path)
+			essentials.Must(err)
+			numProblems += lintLayout(path+": ", f)
+			f.Close()
+		}
+	}
+	if numProblems > 0 {
+		os.Exit(1)
+	}
+}
+
+func lintLayout(prefix string, r io.Reader) int {
+	var layout optishop.Layout
+	if err := json.NewDecoder(r).Decode(&layout); err != nil {
+		essentials.Die(prefix + err.Error())
+	}
+	problems := optishop.ValidateLayout(&layout)
+	for _, problem := range problems {
+		fmt.Println(prefix + problem.String())
+	}
+	return len(problems)
+}
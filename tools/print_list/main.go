@@ -0,0 +1,82 @@
+// Command print_list renders a printable shopping list
+// with a route map for a saved layout.
+//
+// The list is a JSON array of items as returned by
+// /api/list, of which only the name, zone, and price are
+// used. An HTML page is written to standard output.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/optishop-server/optishop"
+	"github.com/unixpickle/optishop-server/optishop/db"
+	"github.com/unixpickle/optishop-server/optishop/visualize"
+	"github.com/unixpickle/optishop-server/serverapi"
+)
+
+func main() {
+	var title string
+	flag.StringVar(&title, "title", "Shopping List", "title of the printed list")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: print_list [flags] <layout.json> <list.json>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var layout optishop.Layout
+	readJSON(flag.Arg(0), &layout)
+	var items []*serverapi.ClientListItem
+	readJSON(flag.Arg(1), &items)
+
+	var entries []*db.ListEntry
+	itemsByID := map[db.ListEntryID]*serverapi.ClientListItem{}
+	for i, item := range items {
+		zone := layout.Zone(item.ZoneName)
+		if zone == nil {
+			essentials.Die("unknown zone for item " + item.Name + ": " + item.ZoneName)
+		}
+		id := db.ListEntryID(strconv.Itoa(i))
+		entries = append(entries, &db.ListEntry{
+			ID: id,
+			Info: &db.ListEntryInfo{
+				Zone:  zone,
+				Floor: layout.ZoneFloor(zone),
+			},
+		})
+		itemsByID[id] = item
+	}
+
+	endpoints, err := serverapi.DefaultRouteEndpoints(&layout)
+	essentials.Must(err)
+	conn := optishop.NewFloorConnectorCached(&layout)
+	paths, sorted, err := serverapi.RoutePaths(context.Background(), entries, &layout, conn,
+		endpoints)
+	essentials.Must(err)
+
+	sortedItems := make([]*serverapi.ClientListItem, len(sorted))
+	for i, entry := range sorted {
+		sortedItems[i] = itemsByID[entry.ID]
+	}
+	data, err := serverapi.PrintableList(title, &layout, paths, sorted, sortedItems,
+		visualize.DefaultTheme)
+	essentials.Must(err)
+	os.Stdout.Write(data)
+}
+
+func readJSON(path string, obj interface{}) {
+	f, err := os.Open(path)
+	essentials.Must(err)
+	defer f.Close()
+	essentials.Must(json.NewDecoder(f).Decode(obj))
+}
@@ -2,10 +2,15 @@
 // Connector on a single floor.
 // The JSON for the layout is read from standard input,
 // and an SVG is written to standard output.
+//
+// With the -animate flag, the path is traced out with an
+// animation.
 package main
 
 import (
 	"encoding/json"
+	"flag"
+	"fmt"
 	"os"
 
 	"github.com/ajstarks/svgo/float"
@@ -17,12 +22,20 @@ import (
 const MarginFrac = 0.1
 
 func main() {
-	if len(os.Args) != 3 {
-		essentials.Die("Usage: render_connector <start_zone> <end_zone>")
+	var animate bool
+	flag.BoolVar(&animate, "animate", false, "animate the path")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: render_connector [flags] <start_zone> <end_zone>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
 	}
 
-	startZone := os.Args[1]
-	endZone := os.Args[2]
+	startZone := flag.Arg(0)
+	endZone := flag.Arg(1)
 
 	var layout optishop.Layout
 	essentials.Must(json.NewDecoder(os.Stdin).Decode(&layout))
@@ -58,12 +71,17 @@ func main() {
 	canvas.Start(width, height)
 
 	visualize.DrawFloors(canvas, &layout)
-	visualize.DrawFloorPath(canvas, &layout, optishop.FloorPath{
+	floorPath := optishop.FloorPath{
 		&optishop.FloorPathStep{
 			Floor: layout.FloorIndex(floor),
 			Path:  path,
 		},
-	})
+	}
+	if animate {
+		visualize.DrawAnimatedRoute(canvas, &layout, []optishop.FloorPath{floorPath})
+	} else {
+		visualize.DrawFloorPath(canvas, &layout, floorPath)
+	}
 
 	canvas.End()
 }
@@ -1,11 +1,12 @@
 // Command render_layout renders a Layout from its JSON
 // representation.
-// The JSON is read from standard input, and an SVG is
-// written to standard output.
+// The JSON is read from standard input, and an SVG or PNG
+// is written to standard output.
 package main
 
 import (
 	"encoding/json"
+	"flag"
 	"os"
 
 	"github.com/ajstarks/svgo/float"
@@ -15,15 +16,34 @@ import (
 )
 
 func main() {
-	var layout optishop.Layout
-	essentials.Must(json.NewDecoder(os.Stdin).Decode(&layout))
-
-	width, height, _ := visualize.MultiFloorGeometry(&layout)
+	var format string
+	var width int
+	var themeName string
+	flag.StringVar(&format, "format", "svg", "output format (svg or png)")
+	flag.IntVar(&width, "width", 1000, "width of PNG output in pixels")
+	flag.StringVar(&themeName, "theme", "default", "rendering theme")
+	flag.Parse()
 
-	canvas := svg.New(os.Stdout)
-	canvas.Start(width, height)
+	theme, ok := visualize.Themes[themeName]
+	if !ok {
+		essentials.Die("unknown theme: " + themeName)
+	}
 
-	visualize.DrawFloors(canvas, &layout)
+	var layout optishop.Layout
+	essentials.Must(json.NewDecoder(os.Stdin).Decode(&layout))
 
-	canvas.End()
+	switch format {
+	case "svg":
+		canvas := svg.New(os.Stdout)
+		theme.StartSVG(canvas, visualize.FullViewport(&layout), "Store map")
+		theme.DrawFloors(canvas, &layout)
+		canvas.End()
+	case "png":
+		canvas, err := visualize.NewRasterCanvas(visualize.FullViewport(&layout), width, theme)
+		essentials.Must(err)
+		canvas.DrawFloors(&layout)
+		essentials.Must(canvas.EncodePNG(os.Stdout))
+	default:
+		essentials.Die("unknown format: " + format)
+	}
 }
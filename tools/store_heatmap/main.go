@@ -0,0 +1,66 @@
+// Command store_heatmap renders a heatmap of how often
+// the zones of a store appear on users' shopping lists.
+//
+// It reads a FileDB data directory directly, so it can be
+// run offline against a copy of the server's data.
+// The store is matched by source, name, and address, and
+// an SVG is written to standard output.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/unixpickle/essentials"
+	"github.com/unixpickle/optishop-server/optishop"
+	"github.com/unixpickle/optishop-server/optishop/db"
+	"github.com/unixpickle/optishop-server/optishop/visualize"
+	"github.com/unixpickle/optishop-server/serverapi"
+)
+
+func main() {
+	var store db.StoreInfo
+	var themeName string
+	var outputJSON bool
+	flag.StringVar(&store.SourceName, "source", "target", "name of the store source")
+	flag.StringVar(&store.StoreName, "store", "", "name of the store")
+	flag.StringVar(&store.StoreAddress, "address", "", "address of the store")
+	flag.StringVar(&themeName, "theme", "default", "rendering theme")
+	flag.BoolVar(&outputJSON, "json", false, "output zone counts as JSON instead of an SVG")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: store_heatmap [flags] <data_dir> <layout.json>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	theme, ok := visualize.Themes[themeName]
+	if !ok {
+		essentials.Die("unknown theme: " + themeName)
+	}
+
+	var layout optishop.Layout
+	f, err := os.Open(flag.Arg(1))
+	essentials.Must(err)
+	essentials.Must(json.NewDecoder(f).Decode(&layout))
+	f.Close()
+
+	freqs, err := serverapi.ZonePopularity(&db.FileDB{Dir: flag.Arg(0)}, &store)
+	essentials.Must(err)
+	if len(freqs) == 0 {
+		fmt.Fprintln(os.Stderr, "warning: no list or history entries found for store")
+	}
+
+	if outputJSON {
+		essentials.Must(json.NewEncoder(os.Stdout).Encode(
+			serverapi.NewClientZoneFrequencies(freqs)))
+	} else {
+		os.Stdout.Write(serverapi.HeatmapSVG(&layout, visualize.FullViewport(&layout), theme,
+			freqs))
+	}
+}